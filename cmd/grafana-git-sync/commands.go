@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"grafana_git_sync/pkg/backup"
+	"grafana_git_sync/pkg/config"
+	"grafana_git_sync/pkg/git"
+	"grafana_git_sync/pkg/grafana"
+	"grafana_git_sync/pkg/logging"
+	"grafana_git_sync/pkg/sync"
+)
+
+// runConfigCommand implements "grafana-git-sync config print [flags]" and returns the exit code
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: grafana-git-sync config print [flags]")
+		return 2
+	}
+
+	cfg, err := config.LoadWithArgs(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runPlanCommand implements "grafana-git-sync plan [flags]": it clones the repository
+// into a temporary directory and lists the files a sync would upload or skip,
+// without contacting Grafana. It returns the exit code.
+func runPlanCommand(args []string) int {
+	cfg, err := config.LoadWithArgs(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	if err := logging.Setup(os.Stderr, cfg.LogFormat, cfg.LogLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	repoDir, err := os.MkdirTemp("", "grafana-git-sync-plan-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(repoDir)
+
+	if _, err := newHTTPTransport(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	gitClient, err := newGitClient(cfg, repoDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to initialize Git client: %v\n", err)
+		return 1
+	}
+	if err := gitClient.Clone(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to clone repository: %v\n", err)
+		return 1
+	}
+
+	// The subdirectories were validated when the configuration was loaded
+	sources, _ := sync.ParseSources(cfg.RepoSubdir)
+	fsys, err := gitClient.TreeFS()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	svc := sync.NewServiceWithSources(sources, cfg.DashboardsDir)
+	svc.SetFilter(sync.NewFilter(cfg.Include, cfg.Exclude))
+	svc.SetGenerateUIDs(cfg.GenerateUIDs)
+	plan, err := svc.Plan(fsys)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, f := range plan.Files {
+		folder := f.Folder
+		if folder == "" {
+			folder = "General"
+		}
+		fmt.Fprintf(w, "sync\t%s\tfolder: %s\n", f.Path, folder)
+	}
+	for _, f := range plan.Skipped {
+		fmt.Fprintf(w, "skip\t%s\t%s\n", f.Path, f.Reason)
+	}
+	w.Flush()
+	fmt.Printf("\n%d dashboard(s) to sync, %d file(s) skipped at %s\n", len(plan.Files), len(plan.Skipped), gitClient.Ref())
+	return 0
+}
+
+// runRollbackCommand implements "grafana-git-sync rollback <sha|previous> [flags]":
+// it asks the running daemon, through the admin API on the health listener, to
+// re-apply the dashboards of an earlier commit. It returns the exit code.
+func runRollbackCommand(args []string) int {
+	if len(args) == 0 || git.ValidateRevision(args[0]) != nil {
+		fmt.Fprintln(os.Stderr, "usage: grafana-git-sync rollback <commit sha|previous> [flags]")
+		return 2
+	}
+
+	cfg, err := config.LoadWithArgs(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load configuration: %v\n", err)
+		return 1
+	}
+	if cfg.AdminToken == "" {
+		fmt.Fprintln(os.Stderr, "❌ The admin API is disabled, set ADMIN_TOKEN to roll back")
+		return 1
+	}
+
+	host, port, err := net.SplitHostPort(cfg.HealthAddr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid health listen address %q: %v\n", cfg.HealthAddr, err)
+		return 1
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	endpoint := fmt.Sprintf("http://%s/admin/rollback?commit=%s", net.JoinHostPort(host, port), url.QueryEscape(args[0]))
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.AdminToken)
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to reach the admin API: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || resp.StatusCode != http.StatusAccepted {
+		if body.Error == "" {
+			body.Error = resp.Status
+		}
+		fmt.Fprintf(os.Stderr, "❌ Rollback request failed: %s\n", body.Error)
+		return 1
+	}
+	fmt.Printf("Rollback to %s queued; sync stays pinned until POST /admin/resume\n", args[0])
+	return 0
+}
+
+// runRestoreCommand implements "grafana-git-sync restore [<backup>] [flags]":
+// without a backup it lists the backups in BACKUP_DIR, otherwise it uploads the
+// dashboards of the backup, named as listed or given as a directory, back to
+// Grafana. It returns the exit code.
+func runRestoreCommand(args []string) int {
+	name := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	cfg, err := config.LoadWithArgs(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load configuration: %v\n", err)
+		return 1
+	}
+	if err := logging.Setup(os.Stderr, cfg.LogFormat, cfg.LogLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	if name == "" {
+		if cfg.BackupDir == "" {
+			fmt.Fprintln(os.Stderr, "❌ BACKUP_DIR is not set")
+			return 1
+		}
+		backups, err := backup.NewStore(cfg.BackupDir, 0, nil).List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, b := range backups {
+			source := "commit " + b.Commit
+			if b.Restored != "" {
+				source = "restore of " + b.Restored
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d dashboard(s)\n", b.Name, b.Created.Local().Format(time.DateTime), source, len(b.Dashboards))
+		}
+		w.Flush()
+		fmt.Printf("\n%d backup(s) in %s\n", len(backups), cfg.BackupDir)
+		return 0
+	}
+
+	transport, err := newHTTPTransport(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	grafanaClient := grafana.NewClient(cfg.GrafanaURL, cfg.GrafanaToken, cfg.GrafanaUser, cfg.GrafanaPass)
+	grafanaClient.SetTransport(transport)
+
+	restored, err := backup.NewStore(cfg.BackupDir, cfg.BackupRetention, grafanaClient).Restore(context.Background(), name)
+	if err != nil && restored == 0 {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Restored %d dashboard(s) from %s, the rest failed: %v\n", restored, name, err)
+		return 1
+	}
+	fmt.Printf("Restored %d dashboard(s) from %s\n", restored, name)
+	return 0
+}
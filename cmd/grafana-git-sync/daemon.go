@@ -0,0 +1,687 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"grafana_git_sync/pkg/admin"
+	"grafana_git_sync/pkg/backup"
+	"grafana_git_sync/pkg/config"
+	"grafana_git_sync/pkg/forge"
+	"grafana_git_sync/pkg/git"
+	"grafana_git_sync/pkg/grafana"
+	"grafana_git_sync/pkg/health"
+	"grafana_git_sync/pkg/logging"
+	"grafana_git_sync/pkg/notify"
+	"grafana_git_sync/pkg/secrets"
+	"grafana_git_sync/pkg/sync"
+	"grafana_git_sync/pkg/tracing"
+)
+
+var tracer = otel.Tracer("grafana_git_sync/cmd/grafana-git-sync")
+
+// daemon runs the poll-and-sync loop
+type daemon struct {
+	args       []string      // command-line arguments, re-read on config reload
+	reloads    chan struct{} // config reload requests from SIGHUP or the file watcher
+	grace      atomic.Int64  // shutdown grace period, read outside the loop
+	cfg        *config.Config
+	health     *health.Checker
+	admin      *admin.Controller
+	grafana    *grafana.Client
+	git        *git.Client
+	sync       *sync.Service
+	notify     *notify.Dispatcher
+	status     *forge.Reporter  // nil when commit statuses are disabled
+	backups    *backup.Store    // nil when backups are disabled
+	secrets    *secrets.Watcher // nil when secrets are not refreshed
+	lastCommit string
+	// file hashes of the last sync before this start, from SYNC_STATE_FILE;
+	// the first run is checked against them
+	savedHashes map[string]string
+	stateErr    error // why SYNC_STATE_FILE could not be read, blocks the first run
+}
+
+// run polls Git until ctx is cancelled. A sync that is in flight when ctx is
+// cancelled may keep running for the configured grace period before it is aborted.
+func (d *daemon) run(ctx context.Context) {
+	// In-flight work runs on its own context that outlives ctx by the grace period
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+	d.grace.Store(int64(d.cfg.ShutdownGrace))
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-workCtx.Done():
+			return
+		}
+		grace := time.Duration(d.grace.Load())
+		slog.Info("Shutdown requested, waiting for in-flight sync", "grace_period", grace)
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			slog.Warn("Grace period expired, aborting in-flight sync")
+			cancelWork()
+		case <-workCtx.Done():
+		}
+	}()
+
+	poll := time.NewTimer(0)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if d.lastCommit != "" {
+				slog.Info("Last synced commit", "commit", d.lastCommit)
+			}
+			return
+		case req := <-d.admin.Requests():
+			d.health.Heartbeat()
+			d.handleRequest(workCtx, req)
+		case <-d.reloads:
+			if d.reloadConfig() {
+				poll.Reset(d.cfg.PollInterval)
+			}
+		case <-poll.C:
+			if ctx.Err() != nil {
+				continue
+			}
+			d.health.Heartbeat()
+			if paused, until := d.admin.Paused(); paused {
+				if pinned := d.admin.Pinned(); pinned != "" {
+					slog.Info("Sync pinned after rollback, skipping poll", "commit", pinned)
+				} else if until.IsZero() {
+					slog.Info("Sync paused, skipping poll")
+				} else {
+					slog.Info("Sync paused, skipping poll", "until", until)
+				}
+			} else {
+				d.syncOnce(workCtx, runOptions{})
+			}
+			poll.Reset(d.cfg.PollInterval)
+		}
+	}
+}
+
+// requestReload asks the loop to reload its configuration; it never blocks
+func (d *daemon) requestReload() {
+	select {
+	case d.reloads <- struct{}{}:
+	default: // a reload is already pending
+	}
+}
+
+// reloadConfig loads the configuration again and applies the settings that are
+// safe to change at runtime. An invalid configuration is rejected and the
+// current one stays in effect. It reports whether the poll interval changed.
+func (d *daemon) reloadConfig() bool {
+	slog.Info("Reloading configuration")
+	next, err := config.LoadWithArgs(d.args)
+	if err != nil {
+		slog.Error("Config reload rejected, keeping current configuration", "error", err)
+		d.health.SetConfigError(err.Error())
+		return false
+	}
+	d.health.SetConfigError("")
+
+	// A token created at startup is not part of the loaded configuration
+	if next.GrafanaToken == "" {
+		next.GrafanaToken = d.cfg.GrafanaToken
+	}
+
+	applied, changes := config.Reload(d.cfg, next)
+	if len(changes) == 0 {
+		slog.Info("Configuration unchanged")
+		return false
+	}
+
+	var reloaded, restart []string
+	for _, c := range changes {
+		if c.Restart {
+			restart = append(restart, c.Key)
+		} else {
+			reloaded = append(reloaded, c.Key)
+		}
+	}
+
+	prev := d.cfg
+	d.cfg = applied
+	d.grace.Store(int64(applied.ShutdownGrace))
+	d.health.SetOptions(health.Options{
+		PollInterval:     applied.PollInterval,
+		StaleAfterPolls:  applied.HealthStaleAfterPolls,
+		HeartbeatTimeout: applied.HealthLivenessTimeout,
+	})
+	d.sync.SetMirror(applied.DashboardsMirror)
+	if applied.LogLevel != prev.LogLevel {
+		// Validated when the configuration was loaded
+		logging.SetLevel(applied.LogLevel)
+	}
+	if !slices.Equal(applied.Include, prev.Include) || !slices.Equal(applied.Exclude, prev.Exclude) {
+		d.sync.SetFilter(sync.NewFilter(applied.Include, applied.Exclude))
+	}
+	if applied.GrafanaToken != prev.GrafanaToken {
+		d.grafana.SetToken(applied.GrafanaToken)
+	}
+	if applied.GrafanaUser != prev.GrafanaUser || applied.GrafanaPass != prev.GrafanaPass {
+		d.grafana.SetBasicAuth(applied.GrafanaUser, applied.GrafanaPass)
+	}
+	if applied.NotifyOptions() != prev.NotifyOptions() {
+		if err := d.notify.Configure(applied.NotifyOptions()); err != nil {
+			slog.Warn("Failed to apply new notification settings", "error", err)
+		}
+	}
+	if applied.GitCredentials() != prev.GitCredentials() {
+		if err := d.git.UpdateCredentials(applied.GitCredentials()); err != nil {
+			slog.Warn("Failed to apply new Git credentials", "error", err)
+		}
+	}
+	if !maps.Equal(applied.SecretRefs, prev.SecretRefs) {
+		d.watchSecrets(context.Background(), applied)
+	}
+
+	if len(reloaded) > 0 {
+		slog.Info("Configuration reloaded", "settings", strings.Join(reloaded, ","))
+	}
+	if len(restart) > 0 {
+		slog.Warn("Restart required to apply settings", "settings", strings.Join(restart, ","))
+	}
+	return applied.PollInterval != prev.PollInterval
+}
+
+// watchSecrets watches the secret references of cfg. A rotated secret reloads
+// the configuration, which applies the new value from the current settings.
+func (d *daemon) watchSecrets(ctx context.Context, cfg *config.Config) {
+	if d.secrets == nil {
+		return
+	}
+	refs := slices.Sorted(maps.Values(cfg.SecretRefs))
+	if err := d.secrets.Set(ctx, refs, func(string) { d.requestReload() }); err != nil {
+		slog.Warn("Cannot watch secret", "error", err)
+	}
+}
+
+// handleRequest runs a manual admin request. Manual requests are served even
+// while paused; while pinned after a rollback they sync the pinned commit.
+func (d *daemon) handleRequest(ctx context.Context, req admin.Request) {
+	slog.Info("Running admin request", "action", req.Action, "path", req.Path, "commit", req.Commit)
+	switch req.Action {
+	case admin.ActionSync:
+		d.syncOnce(ctx, runOptions{})
+	case admin.ActionResync:
+		d.syncOnce(ctx, runOptions{force: true})
+	case admin.ActionSyncPath:
+		d.syncOnce(ctx, runOptions{path: req.Path})
+	case admin.ActionRollback:
+		d.syncOnce(ctx, runOptions{rollback: req.Commit})
+	case admin.ActionOverride:
+		d.syncOnce(ctx, runOptions{override: true, overrideCommit: req.Commit})
+	}
+}
+
+// runOptions modify a single sync run
+type runOptions struct {
+	force    bool   // sync even if the commit is unchanged, ignoring recorded file hashes
+	path     string // only sync this repository path, regardless of its hash
+	rollback string // check out this commit, or "previous", and pin the loop to it
+
+	override       bool   // ignore the safety thresholds
+	overrideCommit string // only override if the synced commit starts with this
+}
+
+// syncOnce fetches the latest commit and uploads changed dashboards
+func (d *daemon) syncOnce(ctx context.Context, opts runOptions) {
+	ctx, span := tracer.Start(ctx, "sync.run", trace.WithAttributes(
+		attribute.Bool("sync.force", opts.force),
+		attribute.String("sync.path", opts.path),
+		attribute.String("sync.rollback", opts.rollback),
+		attribute.Bool("sync.override", opts.override),
+	))
+	defer span.End()
+
+	// The outcome is reported once the run ends; only runs that processed a
+	// commit with dashboard changes are summarised, all count towards alerts
+	event := notify.Event{Time: time.Now(), Ref: d.git.Ref()}
+	processed := false
+	statusCommit := "" // commit whose sync result is reported to the forge
+	defer func() {
+		event.Duration = time.Since(event.Time)
+		d.health.SetLastAttempt(time.Now(), event.Failure())
+		// Report a run cut short by shutdown too
+		ctx := context.WithoutCancel(ctx)
+		d.notify.Record(ctx, event, processed)
+		// A single-path sync says nothing about the commit as a whole
+		if statusCommit != "" && opts.path == "" {
+			d.reportStatus(ctx, statusCommit, event)
+		}
+	}()
+
+	// A rollback, and every run while pinned, checks out a fixed commit instead of fetching the ref
+	rev := opts.rollback
+	if rev == "" {
+		rev = d.admin.Pinned()
+	}
+	var commit string
+	var err error
+	if rev != "" {
+		commit, err = d.git.Checkout(ctx, rev)
+	} else {
+		commit, err = d.git.FetchLatestCommit(ctx)
+	}
+	var sigErr *git.SignatureError
+	if errors.As(err, &sigErr) {
+		// The refusal was logged by the Git client; the last verified commit stays synced
+		tracing.Fail(span, err)
+		event.Error = err.Error()
+		statusCommit = sigErr.Commit.String()
+		d.health.SetSignatureError(sigErr.Error())
+		d.health.SetLastError(err.Error())
+		d.health.SetGitSyncHealth(false)
+		return
+	}
+	if err != nil {
+		if rev != "" {
+			slog.Error("Failed to check out commit", "commit", rev, "error", err)
+		} else {
+			slog.Warn("Failed to fetch latest commit", "error", err)
+		}
+		tracing.Fail(span, err)
+		event.Error = err.Error()
+		d.health.SetLastError(err.Error())
+		d.health.SetGitSyncHealth(false)
+		return
+	}
+	d.health.SetGitSyncHealth(true)
+	d.health.SetSignatureError("")
+	d.health.SetRevision(d.git.Ref(), commit)
+	span.SetAttributes(tracing.Commit.String(commit), tracing.Ref.String(d.git.Ref()))
+	event.Commit, event.Ref, event.CommitURL = commit, d.git.Ref(), d.commitURL(commit)
+	if opts.rollback != "" {
+		// Pin before uploading so a partly failed rollback is not undone by the next poll
+		slog.Info("Rolling back dashboards", "commit", commit, "previous_commit", d.lastCommit)
+		d.admin.Pin(commit)
+	}
+
+	if commit == d.lastCommit && !opts.force && opts.path == "" {
+		slog.Debug("No changes detected", "commit", commit)
+		d.health.SetLastSync(time.Now())
+		return
+	}
+
+	slog.Info("New commit detected", "commit", commit, "ref", d.git.Ref())
+	processed = true
+	statusCommit = commit
+
+	// Get commit information for versioning
+	commitInfo, err := d.git.GetCommitInfo()
+	if err != nil {
+		slog.Warn("Failed to get commit info", "commit", commit, "error", err)
+		commitInfo = nil
+	}
+
+	// Build version message for Grafana
+	versionMessage := ""
+	if commitInfo != nil {
+		event.Message, event.Author = commitInfo.Message, commitInfo.Author
+		// Format: "commit abc123 (tag:v1.2.0): Updated dashboard - John Doe"
+		versionMessage = fmt.Sprintf("commit %s: %s - %s", d.revision(commitInfo.Hash), commitInfo.Message, commitInfo.Author)
+		if rev != "" {
+			versionMessage = "rollback to " + versionMessage
+		}
+		slog.Debug("Version message", "commit", commit, "message", versionMessage)
+	}
+
+	// Read dashboards straight from the commit tree
+	allFiles, err := d.discover(ctx, commit)
+	if err != nil {
+		tracing.Fail(span, err)
+		event.Error = err.Error()
+		d.health.SetLastError(err.Error())
+		return
+	}
+
+	repoPaths := make([]string, len(allFiles))
+	inRepo := make(map[string]bool, len(allFiles))
+	for i, file := range allFiles {
+		repoPaths[i] = d.sync.RepoPath(file)
+		inRepo[repoPaths[i]] = true
+	}
+	// Colliding files are not uploaded; they stay listed as failed until fixed
+	for _, collision := range d.sync.Collisions() {
+		for _, path := range collision.Paths {
+			if inRepo[path] {
+				continue // already reported for another collision
+			}
+			status := health.ResourceStatus{Path: path, LastAttempt: time.Now(), Result: health.ResultFailed, Error: collision.String()}
+			if collision.Kind == sync.CollisionUID {
+				status.UID = collision.Value
+			}
+			d.health.RecordResource(status)
+			event.Errors = append(event.Errors, notify.ResourceError{Path: path, UID: status.UID, Error: status.Error})
+			repoPaths = append(repoPaths, path)
+			inRepo[path] = true
+		}
+	}
+
+	hashes := d.sync.Hashes()
+	var changedFiles []string
+	if opts.path != "" {
+		for _, file := range allFiles {
+			if d.sync.RepoPath(file) == opts.path {
+				changedFiles = append(changedFiles, file)
+			}
+		}
+		if len(changedFiles) == 0 {
+			err := fmt.Errorf("path %s is not a managed dashboard file", opts.path)
+			slog.Error("Path sync failed", "path", opts.path, "error", err)
+			tracing.Fail(span, err)
+			event.Error = err.Error()
+			d.health.SetLastError(err.Error())
+			return
+		}
+		// Record the hash only for this file so other changes are still picked up by the next poll
+		for _, file := range changedFiles {
+			if err := d.sync.RecordHash(file); err != nil {
+				slog.Error("Path sync failed", "path", opts.path, "error", err)
+				tracing.Fail(span, err)
+				event.Error = err.Error()
+				d.health.SetLastError(err.Error())
+				return
+			}
+		}
+	} else {
+		// Smart sync: only process changed files
+		changedFiles, err = d.sync.GetChangedFiles(allFiles)
+		if err != nil {
+			slog.Warn("Failed to detect changed files, syncing all", "error", err)
+			changedFiles = allFiles
+		}
+	}
+
+	// Nothing has been applied yet; stop here if the commit changes too much at once
+	if opts.path == "" && rev == "" {
+		if err := d.checkSafety(commit, commitInfo, opts, allFiles, hashes); err != nil {
+			// Undo the recorded hashes so the changes are detected again once overridden
+			d.sync.SetHashes(hashes)
+			tracing.Fail(span, err)
+			event.Error = err.Error()
+			d.health.SetSyncBlocked(err.Error())
+			d.health.SetLastError(err.Error())
+			return
+		}
+	}
+	d.health.SetSyncBlocked("")
+	event.Removed = d.health.RetainResources(repoPaths)
+	d.sync.WriteMirror()
+
+	if opts.force {
+		slog.Info("Full resync requested, ignoring recorded file hashes")
+		changedFiles = allFiles
+	}
+
+	span.SetAttributes(attribute.Int("sync.dashboards", len(allFiles)), attribute.Int("sync.changed", len(changedFiles)))
+	if len(changedFiles) == 0 {
+		slog.Info("No dashboard changes detected in this commit", "commit", commit)
+		processed = event.Removed > 0 || len(event.Errors) > 0
+		d.health.SetLastSync(time.Now())
+		d.setSynced(commit)
+		return
+	}
+
+	slog.Info("Detected changed dashboards", "commit", commit, "changed", len(changedFiles), "total", len(allFiles))
+
+	// Build folder structure (for all files to ensure folders exist)
+	folderGraph := sync.BuildFolderGraph(allFiles, d.cfg.DashboardsDir)
+
+	// Create folders in Grafana (only root nodes, recursively creates children)
+	for _, node := range folderGraph {
+		if !sync.HasParent(node, folderGraph) {
+			if err := d.grafana.CreateFolderTreeFromNode(ctx, node, ""); err != nil {
+				slog.Error("Failed to create folder tree", "folder", node.FullPath, "error", err)
+				d.health.SetLastError(err.Error())
+			}
+		}
+	}
+
+	// Upload only changed dashboards, saving the ones they overwrite first
+	var uploader sync.Uploader = d.grafana
+	var snapshot *backup.Snapshot
+	if d.backups != nil {
+		snapshot = d.backups.Begin(commit)
+		uploader = backup.Uploader{Uploader: d.grafana, Snapshot: snapshot}
+	}
+	summary := d.sync.UploadDashboards(ctx, uploader, changedFiles, versionMessage, sync.PoolOptions{
+		Workers:   d.cfg.UploadWorkers,
+		RateLimit: d.cfg.UploadRate,
+		OnResult:  func(sync.FileResult) { d.health.Heartbeat() },
+	})
+	if snapshot != nil {
+		if _, err := snapshot.Close(); err != nil {
+			slog.Warn("Failed to remove old backups", "error", err)
+		}
+	}
+	for _, result := range summary.Results {
+		status := health.ResourceStatus{
+			Path:        d.sync.RepoPath(result.FilePath),
+			UID:         result.UID,
+			Folder:      result.FolderPath,
+			Commit:      commit,
+			LastAttempt: time.Now(),
+			Result:      health.ResultSynced,
+		}
+		if result.Err != nil {
+			attrs := []any{"commit", commit, "path", status.Path, "dashboard_uid", result.UID, "folder", result.FolderPath, "error", result.Err}
+			var apiErr *grafana.APIError
+			if errors.As(result.Err, &apiErr) {
+				attrs = append(attrs, "grafana_status", apiErr.StatusCode)
+			}
+			slog.Error("Failed to upload dashboard", attrs...)
+			event.Errors = append(event.Errors, notify.ResourceError{Path: status.Path, UID: result.UID, Error: result.Err.Error()})
+			// Forget the hash so the file is retried on the next poll
+			d.sync.ForgetFile(result.FilePath)
+			status.Result = health.ResultFailed
+			status.Error = result.Err.Error()
+		} else {
+			slog.Info("Uploaded dashboard", "commit", commit, "path", status.Path, "dashboard_uid", result.UID, "folder", result.FolderPath)
+		}
+		d.health.RecordResource(status)
+	}
+
+	slog.Info("Sync completed", "commit", commit, "uploaded", summary.Uploaded, "failed", summary.Failed, "duration", summary.Duration)
+	span.SetAttributes(attribute.Int("sync.uploaded", summary.Uploaded), attribute.Int("sync.failed", summary.Failed))
+	event.Created, event.Updated, event.Failed = summary.Created, summary.Uploaded-summary.Created, summary.Failed
+	if err := summary.Err(); err != nil {
+		tracing.Fail(span, err)
+		event.Error = fmt.Sprintf("%d of %d dashboards failed to sync", summary.Failed, summary.Total)
+		d.health.SetLastError(err.Error())
+		return
+	}
+	d.annotate(ctx, commit, commitInfo, summary.Results)
+	// A single-path sync does not bring the rest of the tree up to date
+	if opts.path != "" {
+		return
+	}
+	d.health.SetLastSync(time.Now())
+	d.health.SetLastError("")
+	d.setSynced(commit)
+}
+
+// setSynced records commit as the last synced commit and, with SYNC_STATE_FILE,
+// saves it with the file hashes for the next start
+func (d *daemon) setSynced(commit string) {
+	d.lastCommit = commit
+	if d.cfg.StateFile == "" {
+		return
+	}
+	if err := sync.SaveState(d.cfg.StateFile, sync.State{Commit: commit, Hashes: d.sync.Hashes()}); err != nil {
+		slog.Warn("Failed to save sync state", "path", d.cfg.StateFile, "error", err)
+	}
+}
+
+// checkSafety returns an error if a run syncing files exceeds the safety
+// thresholds compared with the file hashes of the last sync, and is not
+// overridden by the admin API or the commit message
+func (d *daemon) checkSafety(commit string, info *git.CommitInfo, opts runOptions, files []string, hashes map[string]string) error {
+	thresholds := d.cfg.SafetyThresholds()
+	if thresholds == (sync.Thresholds{}) {
+		return nil
+	}
+	var err error
+	if d.lastCommit == "" {
+		// Nothing is hashed before the first run after a start, so compare
+		// with the state saved by the previous process instead
+		hashes, err = d.savedHashes, d.stateErr
+	}
+	previous, current := len(hashes), len(files)
+	changed, deleted := d.sync.CountChanges(files, hashes)
+	if err == nil {
+		err = thresholds.Check(previous, current, changed, deleted)
+	}
+	if err == nil {
+		return nil
+	}
+
+	marker := d.cfg.SafetyOverrideMarker
+	switch {
+	case opts.override && strings.HasPrefix(commit, opts.overrideCommit):
+		slog.Warn("Safety thresholds overridden via admin API", "commit", commit, "changed", changed, "deleted", deleted)
+		return nil
+	case marker != "" && info != nil && strings.Contains(info.Message, marker):
+		slog.Warn("Safety thresholds overridden by commit message", "commit", commit, "marker", marker, "changed", changed, "deleted", deleted)
+		return nil
+	case opts.override:
+		slog.Warn("Override is for a different commit, safety thresholds still apply", "commit", commit, "override_commit", opts.overrideCommit)
+	}
+	slog.Error("Sync blocked, POST /admin/override or add the override marker to a commit message to apply it",
+		"commit", commit, "changed", changed, "deleted", deleted, "previous", previous, "total", current, "marker", marker, "reason", err)
+	return err
+}
+
+// discover selects the dashboard files in the tree of commit
+func (d *daemon) discover(ctx context.Context, commit string) ([]string, error) {
+	_, span := tracer.Start(ctx, "sync.discover", trace.WithAttributes(tracing.Commit.String(commit)))
+	defer span.End()
+
+	fsys, err := d.git.TreeFS()
+	if err != nil {
+		slog.Error("Failed to read commit tree", "commit", commit, "error", err)
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	files, err := d.sync.ReadDashboards(fsys)
+	if err != nil {
+		slog.Error("Failed to read dashboards", "commit", commit, "error", err)
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("sync.dashboards", len(files)), attribute.Int("sync.skipped", len(d.sync.Skipped())))
+	return files, nil
+}
+
+// revision formats a commit hash for humans, e.g. "abc1234 (tag:v1.2.0)"
+func (d *daemon) revision(hash string) string {
+	shortHash := hash
+	if len(shortHash) > 7 {
+		shortHash = shortHash[:7]
+	}
+	if ref := d.git.Ref(); !strings.HasPrefix(ref, "commit:") {
+		return fmt.Sprintf("%s (%s)", shortHash, ref)
+	}
+	return shortHash
+}
+
+// reportStatus posts the result of syncing commit to the Git forge
+func (d *daemon) reportStatus(ctx context.Context, commit string, event notify.Event) {
+	if d.status == nil {
+		return
+	}
+
+	status := forge.Status{State: forge.StateSuccess, TargetURL: d.cfg.CommitStatusTargetURL}
+	switch {
+	case len(event.Errors) > 0:
+		paths := make([]string, len(event.Errors))
+		for i, e := range event.Errors {
+			paths[i] = e.Path
+		}
+		status.State = forge.StateFailure
+		status.Description = fmt.Sprintf("%d dashboards failed: %s", len(paths), strings.Join(paths, ", "))
+	case event.Failure():
+		status.State = forge.StateFailure
+		status.Description = event.Error
+	case event.Created+event.Updated+event.Removed == 0:
+		status.Description = "Synced to Grafana, no dashboard changes"
+	default:
+		status.Description = fmt.Sprintf("Synced to Grafana: %d created, %d updated", event.Created, event.Updated)
+		if event.Removed > 0 {
+			status.Description += fmt.Sprintf(", %d removed from repository (not deleted in Grafana)", event.Removed)
+		}
+	}
+	if err := d.status.SetStatus(ctx, commit, status); err != nil {
+		slog.Warn("Failed to report commit status", "commit", commit, "error", err)
+	}
+}
+
+// commitURL links to commit in the Git web UI, or returns "" if unknown
+func (d *daemon) commitURL(commit string) string {
+	if d.cfg.CommitURLTemplate != "" {
+		return strings.ReplaceAll(d.cfg.CommitURLTemplate, "{commit}", commit)
+	}
+	return git.CommitURL(d.cfg.RepoURL, commit)
+}
+
+// annotate marks the deployment of commit in Grafana, once globally or on
+// each uploaded dashboard; failures are logged without failing the sync
+func (d *daemon) annotate(ctx context.Context, commit string, info *git.CommitInfo, results []sync.FileResult) {
+	// Validated when the configuration was loaded
+	mode, _ := grafana.ParseAnnotationMode(d.cfg.Annotations)
+	if mode == grafana.AnnotationsOff || len(results) == 0 {
+		return
+	}
+
+	// Annotation text is rendered as HTML, so the commit links to the Git web UI
+	link := d.commitURL(commit)
+	revision := html.EscapeString(d.revision(commit))
+	if link != "" {
+		revision = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(link), revision)
+	}
+	text := "Deployed commit " + revision
+	if info != nil {
+		text += fmt.Sprintf(": %s - %s", html.EscapeString(info.Message), html.EscapeString(info.Author))
+	}
+
+	annotation := grafana.Annotation{Time: time.Now(), Tags: d.cfg.AnnotationTags, Text: text}
+	if mode == grafana.AnnotationsGlobal {
+		if _, err := d.grafana.CreateAnnotation(ctx, annotation); err != nil {
+			slog.Warn("Failed to create annotation", "commit", commit, "error", err)
+		}
+		return
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		if result.UID == "" {
+			slog.Debug("Dashboard has no UID, not annotated", "commit", commit, "path", d.sync.RepoPath(result.FilePath))
+			continue
+		}
+		annotation.DashboardUID = result.UID
+		if _, err := d.grafana.CreateAnnotation(ctx, annotation); err != nil {
+			slog.Warn("Failed to create annotation", "commit", commit, "dashboard_uid", result.UID, "error", err)
+		}
+	}
+}
@@ -1,195 +1,339 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"grafana_git_sync/pkg/admin"
+	"grafana_git_sync/pkg/backup"
 	"grafana_git_sync/pkg/config"
+	"grafana_git_sync/pkg/forge"
 	"grafana_git_sync/pkg/git"
 	"grafana_git_sync/pkg/grafana"
 	"grafana_git_sync/pkg/health"
+	"grafana_git_sync/pkg/logging"
+	"grafana_git_sync/pkg/notify"
+	"grafana_git_sync/pkg/secrets"
 	"grafana_git_sync/pkg/sync"
+	"grafana_git_sync/pkg/tracing"
 )
 
 func main() {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "config":
+			os.Exit(runConfigCommand(args[1:]))
+		case "plan":
+			os.Exit(runPlanCommand(args[1:]))
+		case "rollback":
+			os.Exit(runRollbackCommand(args[1:]))
+		case "restore":
+			os.Exit(runRestoreCommand(args[1:]))
+		}
+	}
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.LoadWithArgs(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatalf("❌ Failed to load configuration: %v", err)
+		fatal("Failed to load configuration", "error", err)
+	}
+	if err := logging.Setup(os.Stderr, cfg.LogFormat, cfg.LogLevel); err != nil {
+		fatal("Failed to set up logging", "error", err)
 	}
-	log.Printf("✅ Loaded configuration: %+v\n", cfg.SafeForLog())
+	slog.Info("Loaded configuration", "config", cfg.SafeForLog())
 
-	log.Println("🚀 Starting Grafana Git Sync sidecar...")
+	slog.Info("Starting Grafana Git Sync sidecar")
+
+	// Cancelled on SIGTERM/SIGINT so Kubernetes pod termination stops the sidecar cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.TracesExporter, os.Stdout)
+	if err != nil {
+		fatal("Failed to set up tracing", "error", err)
+	}
+	defer flushTraces(shutdownTracing)
 
 	// Initialize health checker
-	healthChecker := health.NewChecker()
+	healthChecker := health.NewCheckerWithOptions(health.Options{
+		PollInterval:     cfg.PollInterval,
+		StaleAfterPolls:  cfg.HealthStaleAfterPolls,
+		HeartbeatTimeout: cfg.HealthLivenessTimeout,
+	})
+
+	// Admin API for pausing the poll loop and triggering syncs
+	adminController := admin.NewController(healthChecker)
+	if cfg.AdminToken != "" {
+		healthChecker.Handle("/admin/", adminController.Handler(cfg.AdminToken))
+		slog.Info("Admin API enabled", "path", "/admin/")
+	} else {
+		slog.Info("Admin API disabled (ADMIN_TOKEN not set)")
+	}
 
 	// Start health check server in background
 	go func() {
-		if err := healthChecker.StartServer(":8080"); err != nil {
-			log.Fatalf("❌ Failed to start health check server: %v", err)
+		if err := healthChecker.StartServer(cfg.HealthAddr); err != nil {
+			fatal("Failed to start health check server", "error", err)
 		}
 	}()
 
+	d, err := newDaemon(ctx, cfg, healthChecker, adminController)
+	if err != nil {
+		if ctx.Err() != nil {
+			slog.Info("Shutdown requested during startup")
+			shutdownHealthServer(healthChecker)
+			return
+		}
+		fatal("Startup failed", "error", err)
+	}
+
+	d.args = args
+	startSecretWatcher(ctx, cfg, d)
+	startConfigWatcher(ctx, cfg, d)
+
+	d.run(ctx)
+
+	shutdownHealthServer(healthChecker)
+	slog.Info("Grafana Git Sync stopped")
+}
+
+// newDaemon waits for Grafana, sets up authentication and clones the repository
+func newDaemon(ctx context.Context, cfg *config.Config, healthChecker *health.Checker, adminController *admin.Controller) (*daemon, error) {
+	transport, err := newHTTPTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize Grafana client
 	grafanaClient := grafana.NewClient(cfg.GrafanaURL, cfg.GrafanaToken, cfg.GrafanaUser, cfg.GrafanaPass)
+	grafanaClient.SetTransport(transport)
 
 	// Ensure Grafana is ready
-	if err := grafanaClient.WaitForReady(2 * time.Minute); err != nil {
-		log.Fatalf("❌ Grafana API not ready: %v", err)
+	if err := grafanaClient.WaitForReady(ctx, 2*time.Minute); err != nil {
+		return nil, fmt.Errorf("Grafana API not ready: %w", err)
 	}
 	healthChecker.SetGrafanaHealth(true)
 
 	// Handle service account token creation if needed
 	if cfg.GrafanaToken == "" {
-		log.Println("ℹ️ No Grafana token provided — creating a new Service Account token...")
+		slog.Info("No Grafana token provided, creating a new service account token")
 
-		if err := grafanaClient.ValidateAuth(); err != nil {
-			log.Fatalf("❌ Grafana authentication failed: %v", err)
+		if err := grafanaClient.ValidateAuth(ctx); err != nil {
+			return nil, fmt.Errorf("Grafana authentication failed: %w", err)
 		}
 
-		token, err := grafanaClient.CreateServiceAccountToken("git-sync-sa", "git-sync-token")
+		token, err := grafanaClient.CreateServiceAccountToken(ctx, "git-sync-sa", "git-sync-token")
 		if err != nil {
-			log.Fatalf("❌ Failed to create service account token: %v", err)
+			return nil, fmt.Errorf("failed to create service account token: %w", err)
 		}
 
 		cfg.GrafanaToken = token
-		grafanaClient = grafana.NewClient(cfg.GrafanaURL, token, cfg.GrafanaUser, cfg.GrafanaPass)
-		log.Println("✅ Successfully created new Grafana Service Account token")
+		grafanaClient.SetToken(token)
+		slog.Info("Created new Grafana service account token")
 	} else {
-		log.Println("✅ Using provided Grafana Service Account token")
+		slog.Info("Using provided Grafana service account token")
 	}
 
 	// Initialize Git client
-	gitClient, err := git.NewClient(cfg.RepoURL, cfg.Branch, cfg.RepoDir, cfg.SSHKey, cfg.HTTPSUser, cfg.HTTPSPassword)
+	gitClient, err := newGitClient(cfg, cfg.RepoDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Git client: %w", err)
+	}
+
+	sources, err := sync.ParseSources(cfg.RepoSubdir)
 	if err != nil {
-		log.Fatalf("❌ Failed to initialize Git client: %v", err)
+		return nil, fmt.Errorf("invalid GIT_REPO_SUBDIR: %w", err)
 	}
+	syncService := sync.NewServiceWithSources(sources, cfg.DashboardsDir)
+	syncService.SetMirror(cfg.DashboardsMirror)
+	syncService.SetFilter(sync.NewFilter(cfg.Include, cfg.Exclude))
+	syncService.SetGenerateUIDs(cfg.GenerateUIDs)
 
 	// Clone repository
-	if err := gitClient.Clone(); err != nil {
-		log.Fatalf("❌ Failed to clone repository: %v", err)
+	if err := gitClient.Clone(ctx); err != nil {
+		return nil, fmt.Errorf("failed to clone repository: %w", err)
 	}
 	healthChecker.SetGitSyncHealth(true)
+	healthChecker.SetStarted()
 
-	// Initialize sync service
-	syncService := sync.NewService(cfg.RepoDir, cfg.RepoSubdir, cfg.DashboardsDir)
+	dispatcher, err := notify.NewDispatcher(cfg.NotifyOptions(), &http.Client{Transport: transport, Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	statusReporter, err := newStatusReporter(cfg, &http.Client{Transport: transport, Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up commit statuses: %w", err)
+	}
 
-	var lastCommit string
+	var backups *backup.Store
+	if cfg.BackupDir != "" {
+		backups = backup.NewStore(cfg.BackupDir, cfg.BackupRetention, grafanaClient)
+		slog.Info("Backing up dashboards before overwriting them", "path", cfg.BackupDir, "retention", cfg.BackupRetention)
+	}
 
-	// Main sync loop
-	for {
-		commit, err := gitClient.FetchLatestCommit()
+	d := &daemon{
+		cfg:     cfg,
+		health:  healthChecker,
+		admin:   adminController,
+		grafana: grafanaClient,
+		git:     gitClient,
+		sync:    syncService,
+		notify:  dispatcher,
+		status:  statusReporter,
+		backups: backups,
+		reloads: make(chan struct{}, 1),
+	}
+	if cfg.StateFile != "" {
+		state, err := sync.LoadState(cfg.StateFile)
 		if err != nil {
-			log.Printf("⚠️ Failed to fetch latest commit: %v", err)
-			healthChecker.SetLastError(err.Error())
-			healthChecker.SetGitSyncHealth(false)
-			time.Sleep(cfg.PollInterval)
-			continue
+			// Fail closed: the first sync is blocked until it is overridden
+			slog.Error("Failed to load sync state, blocking the first sync", "path", cfg.StateFile, "error", err)
+			d.stateErr = fmt.Errorf("%w: cannot read SYNC_STATE_FILE: %v", sync.ErrBlocked, err)
+		} else if state.Commit != "" {
+			slog.Info("Loaded sync state", "path", cfg.StateFile, "commit", state.Commit)
 		}
-		healthChecker.SetGitSyncHealth(true)
-
-		if commit != lastCommit {
-			log.Printf("📦 New commit detected: %s", commit)
-
-			// Get commit information for versioning
-			commitInfo, err := gitClient.GetCommitInfo()
-			if err != nil {
-				log.Printf("⚠️ Failed to get commit info: %v", err)
-				commitInfo = nil
-			}
-
-			// Build version message for Grafana
-			versionMessage := ""
-			if commitInfo != nil {
-				// Format: "commit abc123: Updated dashboard - John Doe"
-				shortHash := commitInfo.Hash
-				if len(shortHash) > 7 {
-					shortHash = shortHash[:7]
-				}
-				versionMessage = fmt.Sprintf("commit %s: %s - %s", shortHash, commitInfo.Message, commitInfo.Author)
-				log.Printf("📝 Version: %s", versionMessage)
-			}
+		d.savedHashes = state.Hashes
+	}
+	return d, nil
+}
 
-			// Copy dashboards from repo to dashboards directory
-			allFiles, err := syncService.CopyDashboards()
-			if err != nil {
-				log.Printf("❌ Failed to copy dashboards: %v", err)
-				healthChecker.SetLastError(err.Error())
-				time.Sleep(cfg.PollInterval)
-				continue
-			}
+// newHTTPTransport applies the TLS and proxy settings to Git over HTTP(S) and
+// returns the transport for the Grafana client
+func newHTTPTransport(cfg *config.Config) (*http.Transport, error) {
+	transport, err := cfg.HTTPOptions().Transport()
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS or proxy configuration: %w", err)
+	}
+	git.SetHTTPTransport(transport)
+	return transport, nil
+}
 
-		// Smart sync: only process changed files
-		changedFiles, err := syncService.GetChangedFiles(allFiles)
+// newStatusReporter creates the commit status reporter, or returns nil when
+// commit statuses are disabled
+func newStatusReporter(cfg *config.Config, client *http.Client) (*forge.Reporter, error) {
+	kind, err := forge.ParseKind(cfg.CommitStatus)
+	if err != nil || kind == forge.KindOff {
+		return nil, err
+	}
+	apiURL := cfg.CommitStatusAPIURL
+	token := forge.StaticToken(cfg.CommitStatusToken)
+	if cfg.CommitStatusToken == "" {
+		// Validation ensures the GitHub App used for Git is configured
+		app, err := git.NewGitHubApp(int64(cfg.GitHubAppID), int64(cfg.GitHubInstallID), cfg.GitHubAppKey, cfg.GitHubAPIURL)
 		if err != nil {
-			log.Printf("⚠️ Failed to detect changed files: %v, syncing all", err)
-			changedFiles = allFiles
+			return nil, err
+		}
+		token = app.Token
+		if apiURL == "" {
+			apiURL = cfg.GitHubAPIURL
 		}
+	}
+	reporter, err := forge.NewReporter(kind, apiURL, cfg.RepoURL, cfg.CommitStatusContext, token, client)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Reporting commit statuses", "forge", kind, "context", cfg.CommitStatusContext)
+	return reporter, nil
+}
 
-		if len(changedFiles) == 0 {
-			log.Println("ℹ️ No dashboard changes detected in this commit")
-			lastCommit = commit
-			time.Sleep(cfg.PollInterval)
-			continue
+// newGitClient creates a Git client cloning into repoDir that follows the
+// configured ref and verifies commit signatures if enabled
+func newGitClient(cfg *config.Config, repoDir string) (*git.Client, error) {
+	ref, err := cfg.RefSelector()
+	if err != nil {
+		return nil, fmt.Errorf("invalid GIT_REF: %w", err)
+	}
+	gitClient, err := git.NewClientWithRef(cfg.RepoURL, ref, repoDir, cfg.GitCredentials())
+	if err != nil {
+		return nil, err
+	}
+
+	mode, err := git.ParseVerifyMode(cfg.VerifySignatures)
+	if err != nil {
+		return nil, err
+	}
+	if mode != git.VerifyOff {
+		keyring, err := git.LoadKeyring(cfg.TrustedKeys)
+		if err != nil {
+			return nil, err
 		}
+		gitClient.SetVerification(mode, keyring)
+		slog.Info("Verifying commit signatures", "mode", mode, "keyring", keyring.String())
+	}
+	return gitClient, nil
+}
 
-		log.Printf("📊 Detected %d changed dashboard(s) out of %d total", len(changedFiles), len(allFiles))
+// flushTraces exports the remaining spans before the process exits
+func flushTraces(shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+}
 
-		// Build folder structure (for all files to ensure folders exist)
-		folderGraph := sync.BuildFolderGraph(allFiles, cfg.DashboardsDir)
+// fatal logs msg as an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
 
-		// Create folders in Grafana (only root nodes, recursively creates children)
-		for _, node := range folderGraph {
-			if !sync.HasParent(node, folderGraph) {
-				if err := grafanaClient.CreateFolderTreeFromNode(node, ""); err != nil {
-					log.Printf("❌ Failed to create folder tree %s: %v", node.FullPath, err)
-					healthChecker.SetLastError(err.Error())
-				}
-			}
-		}
+func shutdownHealthServer(healthChecker *health.Checker) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := healthChecker.Shutdown(ctx); err != nil {
+		slog.Warn("Failed to shut down health check server", "error", err)
+	}
+}
 
-		// Upload only changed dashboards
-		dashboardCount := 0
-		for _, filePath := range changedFiles {
-			dashboard, err := syncService.LoadDashboard(filePath)
-			if err != nil {
-				log.Printf("❌ Failed to load dashboard %s: %v", filePath, err)
-				continue
-			}
+// startSecretWatcher re-reads secrets given by reference and reloads the
+// configuration when one is rotated, so the new value is applied like any other
+// change without a restart
+func startSecretWatcher(ctx context.Context, cfg *config.Config, d *daemon) {
+	if cfg.SecretsRefreshInterval <= 0 {
+		return
+	}
 
-			folderID := 0
-			if dashboard.FolderPath != "" {
-				// Find the folder ID from the already-created folder graph
-				folderID = grafanaClient.GetFolderIDByPath(dashboard.FolderPath)
-				if folderID == 0 {
-					log.Printf("⚠️ Folder not found in graph for %s, attempting to create", dashboard.FolderPath)
-					folderID, err = grafanaClient.CreateFolderTree(dashboard.FolderPath)
-					if err != nil {
-						log.Printf("❌ Failed to ensure folder %s: %v", dashboard.FolderPath, err)
-						continue
-					}
-				}
-			}
+	d.secrets = secrets.NewWatcher(cfg.SecretsRefreshInterval)
+	d.watchSecrets(ctx, cfg)
+	if len(cfg.SecretRefs) > 0 {
+		slog.Info("Watching secrets for rotation", "count", len(cfg.SecretRefs), "interval", cfg.SecretsRefreshInterval)
+	}
+	go d.secrets.Run(ctx)
+}
 
-			if err := grafanaClient.UploadDashboardWithVersion(dashboard.Content, folderID, versionMessage); err != nil {
-				log.Printf("❌ Failed to upload dashboard %s: %v", filePath, err)
-				healthChecker.SetLastError(err.Error())
-			} else {
-				log.Printf("✅ Uploaded dashboard: %s", filePath)
-				dashboardCount++
+// startConfigWatcher reloads the configuration on SIGHUP and, when a config file
+// is used, whenever its content changes
+func startConfigWatcher(ctx context.Context, cfg *config.Config, d *daemon) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				slog.Info("SIGHUP received")
+				d.requestReload()
 			}
 		}
+	}()
 
-		log.Printf("✅ Sync completed: %d dashboard(s) updated", dashboardCount)
-		healthChecker.SetLastSync(time.Now())
-		healthChecker.SetLastError("")
-		lastCommit = commit
-		} else {
-			log.Println("🔍 No changes detected")
-		}
-
-		time.Sleep(cfg.PollInterval)
+	if cfg.ConfigFile != "" && cfg.ConfigReloadInterval > 0 {
+		slog.Info("Watching config file for changes", "path", cfg.ConfigFile, "interval", cfg.ConfigReloadInterval)
+		go config.WatchFile(ctx, cfg.ConfigFile, cfg.ConfigReloadInterval, d.requestReload)
 	}
 }
@@ -0,0 +1,278 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"grafana_git_sync/pkg/git"
+)
+
+// Action identifies what a queued admin request asks the sync loop to do
+type Action string
+
+const (
+	// ActionSync runs a sync now, skipping the wait for the next poll
+	ActionSync Action = "sync"
+	// ActionResync uploads every dashboard again, ignoring recorded file hashes
+	ActionResync Action = "resync"
+	// ActionSyncPath uploads a single repository path
+	ActionSyncPath Action = "sync-path"
+	// ActionRollback re-applies the dashboards of an earlier commit and pins the loop to it
+	ActionRollback Action = "rollback"
+	// ActionOverride runs a sync that ignores the safety thresholds
+	ActionOverride Action = "override"
+)
+
+// Request is a manual action queued for the sync loop
+type Request struct {
+	Action Action
+	Path   string
+	Commit string // commit SHA or "previous" for ActionRollback, optional SHA prefix for ActionOverride
+}
+
+// PauseReporter receives pause state changes, typically the health checker
+type PauseReporter interface {
+	SetPaused(paused bool, until time.Time)
+	SetPinned(commit string)
+}
+
+// Controller holds the pause state of the poll loop and queues manual requests
+type Controller struct {
+	mu          sync.Mutex
+	paused      bool
+	pausedUntil time.Time
+	pinned      string // commit rolled back to, kept until Resume
+	requests    chan Request
+	reporter    PauseReporter
+}
+
+// NewController creates a controller; reporter may be nil
+func NewController(reporter PauseReporter) *Controller {
+	return &Controller{
+		requests: make(chan Request, 8),
+		reporter: reporter,
+	}
+}
+
+// Requests returns the channel of queued manual requests
+func (c *Controller) Requests() <-chan Request {
+	return c.requests
+}
+
+// Enqueue queues a manual request, failing if the queue is full
+func (c *Controller) Enqueue(req Request) error {
+	select {
+	case c.requests <- req:
+		return nil
+	default:
+		return fmt.Errorf("request queue is full")
+	}
+}
+
+// Pause stops the poll loop. A positive d resumes it automatically after d.
+// It fails while the loop is pinned, which stays paused until Resume.
+func (c *Controller) Pause(d time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pinned != "" {
+		return fmt.Errorf("sync is pinned to commit %s after a rollback and stays paused until resumed", c.pinned)
+	}
+	c.paused = true
+	c.pausedUntil = time.Time{}
+	if d > 0 {
+		c.pausedUntil = time.Now().Add(d)
+	}
+	c.report()
+	return nil
+}
+
+// Pin pauses the poll loop on commit after a rollback so the next poll does
+// not sync the newer commits again. Resume unpins it.
+func (c *Controller) Pin(commit string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.paused = true
+	c.pausedUntil = time.Time{}
+	c.pinned = commit
+	c.report()
+}
+
+// Pinned returns the commit the loop is pinned to, "" when not pinned
+func (c *Controller) Pinned() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pinned
+}
+
+// Resume restarts the poll loop
+func (c *Controller) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.paused = false
+	c.pausedUntil = time.Time{}
+	c.pinned = ""
+	c.report()
+}
+
+// Paused reports whether the poll loop is paused, resuming it if the pause has expired
+func (c *Controller) Paused() (bool, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused && !c.pausedUntil.IsZero() && time.Now().After(c.pausedUntil) {
+		slog.Info("Pause expired, resuming sync")
+		c.paused = false
+		c.pausedUntil = time.Time{}
+		c.report()
+	}
+	return c.paused, c.pausedUntil
+}
+
+// report must be called with mu held
+func (c *Controller) report() {
+	if c.reporter != nil {
+		c.reporter.SetPaused(c.paused, c.pausedUntil)
+		c.reporter.SetPinned(c.pinned)
+	}
+}
+
+// response is the JSON body returned by admin endpoints
+type response struct {
+	Status      string    `json:"status"`
+	Paused      bool      `json:"paused"`
+	PausedUntil time.Time `json:"paused_until,omitempty"`
+	Pinned      string    `json:"pinned_commit,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Handler returns the admin API. Every request must carry "Authorization: Bearer <token>".
+//
+//	POST /admin/sync[?path=<repo path>]  trigger a sync now, or sync a single path
+//	POST /admin/resync                   force a full resync ignoring file hashes
+//	POST /admin/pause[?duration=30m]     pause the poll loop, optionally auto-resuming
+//	POST /admin/resume                   resume the poll loop, unpinning it after a rollback
+//	POST /admin/rollback?commit=<sha>    re-apply the dashboards of a commit, or "previous", and pin the loop to it
+//	POST /admin/override[?commit=<sha>]  sync once ignoring the safety thresholds, optionally only if at that commit
+func (c *Controller) Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/admin/sync", func(w http.ResponseWriter, r *http.Request) {
+		req := Request{Action: ActionSync}
+		if path := strings.TrimSpace(r.URL.Query().Get("path")); path != "" {
+			req = Request{Action: ActionSyncPath, Path: path}
+		}
+		c.enqueueAndRespond(w, req)
+	})
+
+	mux.HandleFunc("/admin/resync", func(w http.ResponseWriter, r *http.Request) {
+		c.enqueueAndRespond(w, Request{Action: ActionResync})
+	})
+
+	mux.HandleFunc("/admin/rollback", func(w http.ResponseWriter, r *http.Request) {
+		commit := strings.TrimSpace(r.URL.Query().Get("commit"))
+		if err := git.ValidateRevision(commit); err != nil {
+			c.respond(w, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+		c.enqueueAndRespond(w, Request{Action: ActionRollback, Commit: commit})
+	})
+
+	mux.HandleFunc("/admin/override", func(w http.ResponseWriter, r *http.Request) {
+		commit := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("commit")))
+		if commit != "" && !isCommitPrefix(commit) {
+			c.respond(w, http.StatusBadRequest, "error", fmt.Sprintf("invalid commit %q", commit))
+			return
+		}
+		c.enqueueAndRespond(w, Request{Action: ActionOverride, Commit: commit})
+	})
+
+	mux.HandleFunc("/admin/pause", func(w http.ResponseWriter, r *http.Request) {
+		var d time.Duration
+		if raw := r.URL.Query().Get("duration"); raw != "" {
+			parsed, err := time.ParseDuration(raw)
+			if err != nil || parsed < 0 {
+				c.respond(w, http.StatusBadRequest, "error", fmt.Sprintf("invalid duration %q", raw))
+				return
+			}
+			d = parsed
+		}
+		if err := c.Pause(d); err != nil {
+			c.respond(w, http.StatusConflict, "error", err.Error())
+			return
+		}
+		slog.Info("Sync paused via admin API", "duration", d)
+		c.respond(w, http.StatusOK, "paused", "")
+	})
+
+	mux.HandleFunc("/admin/resume", func(w http.ResponseWriter, r *http.Request) {
+		c.Resume()
+		slog.Info("Sync resumed via admin API")
+		c.respond(w, http.StatusOK, "resumed", "")
+	})
+
+	return requireToken(token, requirePost(mux))
+}
+
+func (c *Controller) enqueueAndRespond(w http.ResponseWriter, req Request) {
+	if err := c.Enqueue(req); err != nil {
+		c.respond(w, http.StatusServiceUnavailable, "error", err.Error())
+		return
+	}
+	slog.Info("Admin request queued", "action", req.Action, "path", req.Path, "commit", req.Commit)
+	c.respond(w, http.StatusAccepted, "queued", "")
+}
+
+func (c *Controller) respond(w http.ResponseWriter, code int, status, errMsg string) {
+	paused, until := c.Paused()
+	pinned := c.Pinned()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(response{Status: status, Paused: paused, PausedUntil: until, Pinned: pinned, Error: errMsg}); err != nil {
+		slog.Error("Failed to encode admin response", "error", err)
+	}
+}
+
+// isCommitPrefix reports whether s looks like an abbreviated or full commit SHA
+func isCommitPrefix(s string) bool {
+	if len(s) < 4 || len(s) > 40 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func requirePost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
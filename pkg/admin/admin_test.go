@@ -0,0 +1,182 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeReporter struct {
+	paused bool
+	until  time.Time
+	pinned string
+}
+
+func (f *fakeReporter) SetPaused(paused bool, until time.Time) {
+	f.paused = paused
+	f.until = until
+}
+
+func (f *fakeReporter) SetPinned(commit string) {
+	f.pinned = commit
+}
+
+func doRequest(h http.Handler, method, target, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler_RequiresToken(t *testing.T) {
+	h := NewController(nil).Handler("secret")
+
+	if w := doRequest(h, "POST", "/admin/sync", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", w.Code)
+	}
+	if w := doRequest(h, "POST", "/admin/sync", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with wrong token, got %d", w.Code)
+	}
+	req := httptest.NewRequest("POST", "/admin/sync", nil)
+	req.Header.Set("Authorization", "secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a token without the Bearer scheme, got %d", w.Code)
+	}
+	if w := doRequest(h, "GET", "/admin/sync", "secret"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET, got %d", w.Code)
+	}
+}
+
+func TestHandler_QueuesRequests(t *testing.T) {
+	c := NewController(nil)
+	h := c.Handler("secret")
+
+	tests := []struct {
+		target string
+		want   Request
+	}{
+		{"/admin/sync", Request{Action: ActionSync}},
+		{"/admin/resync", Request{Action: ActionResync}},
+		{"/admin/sync?path=dashboards/cpu.json", Request{Action: ActionSyncPath, Path: "dashboards/cpu.json"}},
+		{"/admin/rollback?commit=previous", Request{Action: ActionRollback, Commit: "previous"}},
+		{"/admin/rollback?commit=0123456789abcdef0123456789abcdef01234567", Request{Action: ActionRollback, Commit: "0123456789abcdef0123456789abcdef01234567"}},
+		{"/admin/override", Request{Action: ActionOverride}},
+		{"/admin/override?commit=ABC1234", Request{Action: ActionOverride, Commit: "abc1234"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if w := doRequest(h, "POST", tt.target, "secret"); w.Code != http.StatusAccepted {
+				t.Fatalf("Expected 202, got %d", w.Code)
+			}
+			select {
+			case got := <-c.Requests():
+				if got != tt.want {
+					t.Errorf("Queued %+v, want %+v", got, tt.want)
+				}
+			default:
+				t.Error("No request was queued")
+			}
+		})
+	}
+}
+
+func TestHandler_PauseResume(t *testing.T) {
+	reporter := &fakeReporter{}
+	c := NewController(reporter)
+	h := c.Handler("secret")
+
+	if w := doRequest(h, "POST", "/admin/pause?duration=bogus", "secret"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid duration, got %d", w.Code)
+	}
+
+	if w := doRequest(h, "POST", "/admin/pause?duration=1h", "secret"); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for pause, got %d", w.Code)
+	}
+	paused, until := c.Paused()
+	if !paused || until.IsZero() {
+		t.Errorf("Expected paused with auto-resume time, got paused=%v until=%v", paused, until)
+	}
+	if !reporter.paused {
+		t.Error("Pause was not reported")
+	}
+
+	if w := doRequest(h, "POST", "/admin/resume", "secret"); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for resume, got %d", w.Code)
+	}
+	if paused, _ := c.Paused(); paused || reporter.paused {
+		t.Error("Expected controller to be resumed")
+	}
+}
+
+func TestHandler_Rollback(t *testing.T) {
+	reporter := &fakeReporter{}
+	c := NewController(reporter)
+	h := c.Handler("secret")
+
+	for _, target := range []string{"/admin/rollback", "/admin/rollback?commit=abc1234", "/admin/override?commit=abc", "/admin/override?commit=main"} {
+		if w := doRequest(h, "POST", target, "secret"); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", target, w.Code)
+		}
+	}
+
+	c.Pin("abc123")
+	if paused, until := c.Paused(); !paused || !until.IsZero() || reporter.pinned != "abc123" {
+		t.Errorf("Expected pinned pause, got paused=%v until=%v pinned=%q", paused, until, reporter.pinned)
+	}
+	// A timed pause must not end the pin
+	if err := c.Pause(time.Millisecond); err == nil {
+		t.Error("Pause() should fail while pinned")
+	}
+	if w := doRequest(h, "POST", "/admin/pause?duration=1ms", "secret"); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "pinned") {
+		t.Errorf("Expected 409 for a pause while pinned, got %d %s", w.Code, w.Body.String())
+	}
+	time.Sleep(5 * time.Millisecond)
+	if paused, _ := c.Paused(); !paused || c.Pinned() != "abc123" {
+		t.Error("Expected the pin to outlive a timed pause")
+	}
+
+	w := doRequest(h, "POST", "/admin/resume", "secret")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "pinned_commit") {
+		t.Fatalf("Expected resume to unpin, got %d %s", w.Code, w.Body.String())
+	}
+	if paused, _ := c.Paused(); paused || c.Pinned() != "" || reporter.pinned != "" {
+		t.Error("Expected controller to be resumed and unpinned")
+	}
+}
+
+func TestPaused_AutoResume(t *testing.T) {
+	reporter := &fakeReporter{}
+	c := NewController(reporter)
+
+	if err := c.Pause(time.Millisecond); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if paused, _ := c.Paused(); paused {
+		t.Error("Expected pause to expire")
+	}
+	if reporter.paused {
+		t.Error("Expected auto-resume to be reported")
+	}
+}
+
+func TestEnqueue_Full(t *testing.T) {
+	c := NewController(nil)
+	for i := 0; i < cap(c.requests); i++ {
+		if err := c.Enqueue(Request{Action: ActionSync}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	if err := c.Enqueue(Request{Action: ActionSync}); err == nil {
+		t.Error("Enqueue() should fail when the queue is full")
+	}
+}
@@ -0,0 +1,378 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	gosync "sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+
+	"grafana_git_sync/pkg/grafana"
+	"grafana_git_sync/pkg/sync"
+	"grafana_git_sync/pkg/tracing"
+)
+
+var tracer = otel.Tracer("grafana_git_sync/pkg/backup")
+
+// manifestFile lists the contents of a backup directory
+const manifestFile = "manifest.json"
+
+// Grafana is the subset of the Grafana client used to back up and restore dashboards
+type Grafana interface {
+	GetDashboard(ctx context.Context, uid string) (*grafana.DashboardState, error)
+	GetFolderPath(ctx context.Context, uid string) (string, error)
+	GetDashboardPermissions(ctx context.Context, uid string) ([]grafana.Permission, error)
+	CreateFolderTree(ctx context.Context, folderPath string) (int, error)
+	UploadDashboardWithVersion(ctx context.Context, dashboard map[string]interface{}, folderID int, versionMessage string) (bool, error)
+	SetDashboardPermissions(ctx context.Context, uid string, permissions []grafana.Permission) error
+}
+
+// Manifest describes the contents of a backup
+type Manifest struct {
+	Created    time.Time `json:"created"`
+	Commit     string    `json:"commit,omitempty"`   // commit whose sync overwrote the dashboards
+	Restored   string    `json:"restored,omitempty"` // backup whose restore overwrote the dashboards
+	Dashboards []Entry   `json:"dashboards"`
+}
+
+// Entry is a backed up dashboard
+type Entry struct {
+	UID         string               `json:"uid"`
+	Title       string               `json:"title"`
+	Folder      string               `json:"folder,omitempty"` // folder path, empty for the General folder
+	FolderUID   string               `json:"folder_uid,omitempty"`
+	Version     int                  `json:"version"`
+	File        string               `json:"file"`        // dashboard JSON, relative to the backup directory
+	Permissions []grafana.Permission `json:"permissions"` // null when they could not be read
+}
+
+// Backup is a backup directory found in the store
+type Backup struct {
+	Name string
+	Manifest
+}
+
+// Store keeps timestamped backups, one directory per sync run, in dir
+type Store struct {
+	dir       string
+	retention int // number of backups kept, 0 keeps all
+	client    Grafana
+}
+
+// NewStore creates a store in dir; client may be nil if the store is only listed
+func NewStore(dir string, retention int, client Grafana) *Store {
+	return &Store{dir: dir, retention: retention, client: client}
+}
+
+// Begin starts the backup of a sync run for commit. Nothing is written until
+// the first dashboard is saved.
+func (s *Store) Begin(commit string) *Snapshot {
+	return &Snapshot{
+		store:    s,
+		saved:    make(map[string]bool),
+		manifest: Manifest{Created: time.Now().UTC(), Commit: commit, Dashboards: []Entry{}},
+	}
+}
+
+// List returns the backups in the store, oldest first
+func (s *Store) List() ([]Backup, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		// Directories without a manifest are not backups and are left alone
+		manifest, err := readManifest(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Name: entry.Name(), Manifest: *manifest})
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].Created.Before(backups[j].Created)
+	})
+	return backups, nil
+}
+
+// Open returns the directory and manifest of a backup. name is a backup in
+// the store or the path of a backup directory.
+func (s *Store) Open(name string) (string, *Manifest, error) {
+	dir := name
+	if !filepath.IsAbs(name) && filepath.Base(name) == name {
+		dir = filepath.Join(s.dir, name)
+	}
+	manifest, err := readManifest(dir)
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot read backup %s: %w", name, err)
+	}
+	return dir, manifest, nil
+}
+
+// Restore uploads every dashboard of the backup name back to its folder and
+// reapplies its permissions. The dashboards it overwrites are backed up first
+// unless the store has no directory. Returns the number of dashboards restored.
+func (s *Store) Restore(ctx context.Context, name string) (int, error) {
+	dir, manifest, err := s.Open(name)
+	if err != nil {
+		return 0, err
+	}
+
+	var snapshot *Snapshot
+	if s.dir != "" {
+		snapshot = s.Begin("")
+		snapshot.manifest.Restored = filepath.Base(dir)
+		// Not pruned, which could remove the backup being restored; the next sync prunes
+		defer snapshot.finish()
+	}
+
+	message := "restore of backup " + filepath.Base(dir)
+	restored := 0
+	var errs []error
+	for _, entry := range manifest.Dashboards {
+		if err := s.restore(ctx, snapshot, dir, entry, message); err != nil {
+			slog.Error("Failed to restore dashboard", "dashboard_uid", entry.UID, "folder", entry.Folder, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", entry.UID, err))
+			continue
+		}
+		slog.Info("Restored dashboard", "dashboard_uid", entry.UID, "folder", entry.Folder, "version", entry.Version)
+		restored++
+	}
+	return restored, errors.Join(errs...)
+}
+
+// restore uploads a single backed up dashboard
+func (s *Store) restore(ctx context.Context, snapshot *Snapshot, dir string, entry Entry, message string) error {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(entry.File)))
+	if err != nil {
+		return err
+	}
+	var dashboard map[string]interface{}
+	if err := json.Unmarshal(data, &dashboard); err != nil {
+		return fmt.Errorf("invalid dashboard JSON: %w", err)
+	}
+	// The numeric ID may belong to another dashboard once this one was deleted
+	dashboard["id"] = nil
+
+	if snapshot != nil {
+		if err := snapshot.Save(ctx, entry.UID); err != nil {
+			return fmt.Errorf("backup failed, dashboard not overwritten: %w", err)
+		}
+	}
+
+	folderID := 0
+	if entry.Folder != "" {
+		if folderID, err = s.client.CreateFolderTree(ctx, entry.Folder); err != nil {
+			return fmt.Errorf("failed to ensure folder %s: %w", entry.Folder, err)
+		}
+	}
+	if _, err := s.client.UploadDashboardWithVersion(ctx, dashboard, folderID, message); err != nil {
+		return err
+	}
+	if entry.Permissions != nil {
+		if err := s.client.SetDashboardPermissions(ctx, entry.UID, entry.Permissions); err != nil {
+			return fmt.Errorf("failed to restore permissions: %w", err)
+		}
+	}
+	return nil
+}
+
+// prune removes the oldest backups beyond the retention limit
+func (s *Store) prune() error {
+	if s.retention <= 0 {
+		return nil
+	}
+	backups, err := s.List()
+	if err != nil {
+		return err
+	}
+	for len(backups) > s.retention {
+		if err := os.RemoveAll(filepath.Join(s.dir, backups[0].Name)); err != nil {
+			return err
+		}
+		slog.Debug("Removed old backup", "backup", backups[0].Name)
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// Snapshot collects the dashboards overwritten by one sync run. It is safe
+// for concurrent use by the upload workers.
+type Snapshot struct {
+	store *Store
+
+	mu       gosync.Mutex
+	dir      string // created with the first saved dashboard
+	manifest Manifest
+	saved    map[string]bool
+}
+
+// Save writes the current state of the dashboard with uid to the backup. A
+// dashboard that does not exist yet, or was already saved, is skipped.
+func (b *Snapshot) Save(ctx context.Context, uid string) (err error) {
+	b.mu.Lock()
+	done := b.saved[uid]
+	b.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	ctx, span := tracer.Start(ctx, "backup.save", trace.WithAttributes(tracing.DashboardUID.String(uid)))
+	defer func() {
+		if err != nil {
+			tracing.Fail(span, err)
+		}
+		span.End()
+	}()
+
+	state, err := b.store.client.GetDashboard(ctx, uid)
+	if err != nil || state == nil {
+		return err
+	}
+	entry := Entry{UID: uid, FolderUID: state.FolderUID, Version: state.Version}
+	entry.Title, _ = state.Dashboard["title"].(string)
+	if state.FolderUID != "" {
+		if entry.Folder, err = b.store.client.GetFolderPath(ctx, state.FolderUID); err != nil {
+			return fmt.Errorf("failed to read folder %s: %w", state.FolderUID, err)
+		}
+	}
+	// Reading permissions needs admin rights; the dashboard is still worth keeping without them
+	if permissions, err := b.store.client.GetDashboardPermissions(ctx, uid); err != nil {
+		slog.Warn("Failed to back up dashboard permissions", "dashboard_uid", uid, "error", err)
+	} else {
+		entry.Permissions = append([]grafana.Permission{}, permissions...)
+	}
+	data, err := json.MarshalIndent(state.Dashboard, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.create(); err != nil {
+		return err
+	}
+	entry.File = "dashboards/" + unsafeChars.ReplaceAllString(uid, "_") + ".json"
+	if err := os.WriteFile(filepath.Join(b.dir, filepath.FromSlash(entry.File)), data, 0600); err != nil {
+		return err
+	}
+	b.manifest.Dashboards = append(b.manifest.Dashboards, entry)
+	b.saved[uid] = true
+	// Keep the manifest current so an interrupted run still leaves a usable backup
+	return writeManifest(b.dir, &b.manifest)
+}
+
+// Close prunes old backups if this one was written and returns its
+// directory, or "" when no dashboard was overwritten
+func (b *Snapshot) Close() (string, error) {
+	dir := b.finish()
+	if dir == "" {
+		return "", nil
+	}
+	return dir, b.store.prune()
+}
+
+// finish logs the written backup and returns its directory
+func (b *Snapshot) finish() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.dir != "" {
+		slog.Info("Backup written", "path", b.dir, "dashboards", len(b.manifest.Dashboards))
+	}
+	return b.dir
+}
+
+// create makes the backup directory, named after the time and commit so
+// names sort chronologically, e.g. 20251201T100000Z-abc1234. Must be called with mu held.
+func (b *Snapshot) create() error {
+	if b.dir != "" {
+		return nil
+	}
+	if err := os.MkdirAll(b.store.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	name := b.manifest.Created.Format("20060102T150405Z")
+	if commit := b.manifest.Commit; commit != "" {
+		name += "-" + commit[:min(len(commit), 7)]
+	} else if b.manifest.Restored != "" {
+		name += "-restore"
+	}
+	dir := filepath.Join(b.store.dir, name)
+	for i := 2; ; i++ {
+		err := os.Mkdir(dir, 0700)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("failed to create backup directory: %w", err)
+		}
+		dir = filepath.Join(b.store.dir, fmt.Sprintf("%s-%d", name, i))
+	}
+	if err := os.Mkdir(filepath.Join(dir, "dashboards"), 0700); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	b.dir = dir
+	return nil
+}
+
+// unsafeChars are replaced in file names derived from dashboard UIDs
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+func readManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", manifestFile, err)
+	}
+	return &manifest, nil
+}
+
+func writeManifest(dir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Replace atomically so a crash never leaves a truncated manifest
+	tmp := filepath.Join(dir, manifestFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, manifestFile))
+}
+
+// Uploader backs up each dashboard before the wrapped uploader overwrites it.
+// A dashboard whose backup fails is not uploaded.
+type Uploader struct {
+	sync.Uploader
+	Snapshot *Snapshot
+}
+
+// UploadDashboardWithVersion saves the current dashboard, then uploads the new one
+func (u Uploader) UploadDashboardWithVersion(ctx context.Context, dashboard map[string]interface{}, folderID int, versionMessage string) (bool, error) {
+	// Without a UID Grafana creates a new dashboard, nothing is overwritten
+	if uid, _ := dashboard["uid"].(string); uid != "" {
+		if err := u.Snapshot.Save(ctx, uid); err != nil {
+			return false, fmt.Errorf("backup failed, dashboard not overwritten: %w", err)
+		}
+	}
+	return u.Uploader.UploadDashboardWithVersion(ctx, dashboard, folderID, versionMessage)
+}
@@ -0,0 +1,189 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"grafana_git_sync/pkg/grafana"
+)
+
+// fakeGrafana stores dashboards in memory
+type fakeGrafana struct {
+	dashboards  map[string]*grafana.DashboardState
+	permissions map[string][]grafana.Permission
+	folders     map[string]string // folder UID -> path
+	uploads     []string          // "uid in folder: message"
+	failGet     bool
+}
+
+func newFakeGrafana() *fakeGrafana {
+	return &fakeGrafana{
+		dashboards: map[string]*grafana.DashboardState{
+			"cpu": {Dashboard: map[string]interface{}{"id": 12.0, "uid": "cpu", "title": "CPU"}, FolderUID: "f1", FolderTitle: "api", Version: 3},
+		},
+		permissions: map[string][]grafana.Permission{"cpu": {{TeamID: 2, Permission: 2}}},
+		folders:     map[string]string{"f1": "Team A/api"},
+	}
+}
+
+func (f *fakeGrafana) GetDashboard(ctx context.Context, uid string) (*grafana.DashboardState, error) {
+	if f.failGet {
+		return nil, errors.New("connection refused")
+	}
+	return f.dashboards[uid], nil
+}
+
+func (f *fakeGrafana) GetFolderPath(ctx context.Context, uid string) (string, error) {
+	return f.folders[uid], nil
+}
+
+func (f *fakeGrafana) GetDashboardPermissions(ctx context.Context, uid string) ([]grafana.Permission, error) {
+	return f.permissions[uid], nil
+}
+
+func (f *fakeGrafana) GetFolderIDByPath(folderPath string) int { return 0 }
+
+func (f *fakeGrafana) CreateFolderTree(ctx context.Context, folderPath string) (int, error) {
+	return len(folderPath), nil
+}
+
+func (f *fakeGrafana) UploadDashboardWithVersion(ctx context.Context, dashboard map[string]interface{}, folderID int, versionMessage string) (bool, error) {
+	uid := dashboard["uid"].(string)
+	f.uploads = append(f.uploads, fmt.Sprintf("%s in %d: %s", uid, folderID, versionMessage))
+	if dashboard["id"] != nil {
+		return false, errors.New("stale dashboard id")
+	}
+	f.dashboards[uid] = &grafana.DashboardState{Dashboard: dashboard, Version: 4}
+	return false, nil
+}
+
+func (f *fakeGrafana) SetDashboardPermissions(ctx context.Context, uid string, permissions []grafana.Permission) error {
+	f.permissions[uid] = permissions
+	return nil
+}
+
+func TestUploader_BacksUpBeforeOverwrite(t *testing.T) {
+	fake := newFakeGrafana()
+	store := NewStore(t.TempDir(), 0, fake)
+	snapshot := store.Begin("abc1234def")
+	uploader := Uploader{Uploader: fake, Snapshot: snapshot}
+
+	for _, uid := range []string{"cpu", "new", "cpu"} {
+		dashboard := map[string]interface{}{"uid": uid, "title": "from git"}
+		if _, err := uploader.UploadDashboardWithVersion(context.Background(), dashboard, 0, "commit abc1234"); err != nil {
+			t.Fatalf("UploadDashboardWithVersion(%s) error = %v", uid, err)
+		}
+	}
+	dir, err := snapshot.Close()
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !strings.HasSuffix(dir, "-abc1234") {
+		t.Errorf("backup directory %s is not named after the commit", dir)
+	}
+
+	_, manifest, err := store.Open(filepath.Base(dir))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	// The new dashboard had nothing to back up and the second upload keeps the first backup
+	if len(manifest.Dashboards) != 1 {
+		t.Fatalf("manifest has %d dashboards, want 1: %+v", len(manifest.Dashboards), manifest.Dashboards)
+	}
+	entry := manifest.Dashboards[0]
+	if entry.UID != "cpu" || entry.Title != "CPU" || entry.Folder != "Team A/api" || entry.Version != 3 || len(entry.Permissions) != 1 {
+		t.Errorf("entry = %+v", entry)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, entry.File))
+	if err != nil || !strings.Contains(string(data), `"title": "CPU"`) {
+		t.Errorf("backed up dashboard = %s, %v", data, err)
+	}
+
+	fake.failGet = true
+	snapshot = store.Begin("def5678")
+	if _, err := (Uploader{Uploader: fake, Snapshot: snapshot}).UploadDashboardWithVersion(context.Background(), map[string]interface{}{"uid": "mem"}, 0, ""); err == nil {
+		t.Error("UploadDashboardWithVersion() should not overwrite a dashboard that could not be backed up")
+	}
+	if _, ok := fake.dashboards["mem"]; ok {
+		t.Error("dashboard was uploaded although its backup failed")
+	}
+}
+
+func TestStore_Restore(t *testing.T) {
+	fake := newFakeGrafana()
+	store := NewStore(t.TempDir(), 0, fake)
+	snapshot := store.Begin("abc1234")
+	if err := snapshot.Save(context.Background(), "cpu"); err != nil {
+		t.Fatal(err)
+	}
+	dir, _ := snapshot.Close()
+
+	// Delete the dashboard and its permissions, then restore them
+	delete(fake.dashboards, "cpu")
+	fake.permissions["cpu"] = nil
+	restored, err := store.Restore(context.Background(), filepath.Base(dir))
+	if err != nil || restored != 1 {
+		t.Fatalf("Restore() = %d, %v", restored, err)
+	}
+	want := fmt.Sprintf("cpu in %d: restore of backup %s", len("Team A/api"), filepath.Base(dir))
+	if len(fake.uploads) != 1 || fake.uploads[0] != want {
+		t.Errorf("uploads = %v, want [%s]", fake.uploads, want)
+	}
+	if got := fake.permissions["cpu"]; len(got) != 1 || got[0].TeamID != 2 {
+		t.Errorf("permissions = %+v, want the backed up team permission", got)
+	}
+
+	// Restoring again backs up the restored dashboard first
+	if _, err := store.Restore(context.Background(), dir); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	backups, err := store.List()
+	if err != nil || len(backups) != 2 || backups[1].Restored != filepath.Base(dir) {
+		t.Errorf("List() = %+v, %v, want the restore backup last", backups, err)
+	}
+
+	if _, err := store.Restore(context.Background(), "missing"); err == nil {
+		t.Error("Restore() should fail for an unknown backup")
+	}
+}
+
+func TestStore_Retention(t *testing.T) {
+	fake := newFakeGrafana()
+	dir := t.TempDir()
+	store := NewStore(dir, 2, fake)
+	// Not a backup, must survive pruning
+	if err := os.Mkdir(filepath.Join(dir, "notes"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for i := 0; i < 4; i++ {
+		snapshot := store.Begin(fmt.Sprintf("commit%d", i))
+		snapshot.manifest.Created = time.Date(2025, 12, 1, 10, i, 0, 0, time.UTC)
+		if err := snapshot.Save(context.Background(), "cpu"); err != nil {
+			t.Fatal(err)
+		}
+		path, err := snapshot.Close()
+		if err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		names = append(names, filepath.Base(path))
+	}
+
+	backups, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || backups[0].Name != names[2] || backups[1].Name != names[3] {
+		t.Errorf("kept %+v, want the two newest of %v", backups, names)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes")); err != nil {
+		t.Errorf("non-backup directory was removed: %v", err)
+	}
+}
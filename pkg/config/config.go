@@ -1,55 +1,171 @@
 package config
 
 import (
+	"bytes"
+	"context"
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"grafana_git_sync/pkg/forge"
+	"grafana_git_sync/pkg/git"
+	"grafana_git_sync/pkg/grafana"
+	"grafana_git_sync/pkg/httpclient"
+	"grafana_git_sync/pkg/logging"
+	"grafana_git_sync/pkg/notify"
+	"grafana_git_sync/pkg/secrets"
+	"grafana_git_sync/pkg/sync"
+	"grafana_git_sync/pkg/tracing"
 )
 
-// Config holds all application configuration
+// Config holds all application configuration.
+//
+// Every field can be set, from lowest to highest precedence, by its default
+// value, the YAML config file (yaml tag), an environment variable (env tag) and
+// a command-line flag (the yaml key with dashes, e.g. --poll-interval).
+//
+// Fields tagged reload:"restart" are not applied by a hot reload.
+//
+// Fields tagged secret can instead be read through a secret reference, usually a
+// file path, using the "_file" key, the "_FILE" variable or the "-file" flag,
+// e.g. GF_SECURITY_TOKEN_FILE=/run/secrets/grafana_token.
 type Config struct {
-	RepoURL       string
-	Branch        string
-	SSHKey        string
-	HTTPSUser     string
-	HTTPSPassword string
-	RepoDir       string
-	RepoSubdir    string
-	DashboardsDir string
-	PollInterval  time.Duration
-	GrafanaURL    string
-	GrafanaUser   string
-	GrafanaPass   string
-	GrafanaToken  string
-}
-
-// Load reads and validates configuration from environment variables
+	RepoURL          string        `yaml:"repo_url" env:"GIT_REPO_URL" reload:"restart" desc:"Git repository URL (SSH or HTTPS)"`
+	Branch           string        `yaml:"branch" env:"GIT_BRANCH" reload:"restart" desc:"Git branch to sync"`
+	Ref              string        `yaml:"ref" env:"GIT_REF" reload:"restart" desc:"revision to sync instead of the branch head: branch:<name>, tag:<name>, commit:<sha> or semver:<range>, e.g. semver:v2.*"`
+	SSHKey           string        `yaml:"ssh_key" env:"GIT_SSH_KEY" secret:"true" desc:"SSH private key for Git"`
+	SSHKeyPassphrase string        `yaml:"ssh_key_passphrase" env:"GIT_SSH_KEY_PASSPHRASE" secret:"true" desc:"passphrase of an encrypted SSH private key"`
+	SSHAgent         bool          `yaml:"ssh_agent" env:"GIT_SSH_AGENT" desc:"authenticate with the SSH agent at SSH_AUTH_SOCK instead of an SSH key"`
+	SSHUser          string        `yaml:"ssh_user" env:"GIT_SSH_USER" desc:"SSH user (default the user in the repository URL, otherwise git)"`
+	HTTPSUser        string        `yaml:"https_user" env:"GIT_HTTPS_USER" desc:"Git HTTPS username"`
+	HTTPSPassword    string        `yaml:"https_password" env:"GIT_HTTPS_PASS" secret:"true" desc:"Git HTTPS password or token"`
+	HTTPSToken       string        `yaml:"https_token" env:"GIT_HTTPS_TOKEN" secret:"true" desc:"bearer token for Git over HTTPS, e.g. a GitLab or Gitea access token"`
+	GitHubAppID      int           `yaml:"github_app_id" env:"GITHUB_APP_ID" desc:"GitHub App ID, to access the repository with short-lived installation tokens"`
+	GitHubInstallID  int           `yaml:"github_app_installation_id" env:"GITHUB_APP_INSTALLATION_ID" desc:"GitHub App installation ID"`
+	GitHubAppKey     string        `yaml:"github_app_private_key" env:"GITHUB_APP_PRIVATE_KEY" secret:"true" desc:"GitHub App private key (PEM)"`
+	GitHubAPIURL     string        `yaml:"github_api_url" env:"GITHUB_API_URL" default:"https://api.github.com" desc:"GitHub API URL used to create installation tokens, https://<host>/api/v3 for GitHub Enterprise Server"`
+	VerifySignatures string        `yaml:"verify_signatures" env:"GIT_VERIFY_SIGNATURES" reload:"restart" default:"off" desc:"refuse commits not signed by a trusted key: off, head (the synced commit) or all (every commit since the last sync)"`
+	TrustedKeys      string        `yaml:"trusted_keys" env:"GIT_TRUSTED_KEYS" reload:"restart" desc:"file with the keys trusted to sign commits: armored OpenPGP public keys and SSH public keys, one per line"`
+	RepoDir          string        `yaml:"repo_dir" env:"GIT_LOCAL_REPO_DIR" reload:"restart" default:"/tmp/grafana_data" desc:"local directory for the Git clone"`
+	RepoSubdir       string        `yaml:"repo_subdir" env:"GIT_REPO_SUBDIR" reload:"restart" desc:"repository subdirectories containing dashboards, comma-separated, each optionally mapped to a Grafana root folder with =, e.g. teams/a=Team A,shared"`
+	DashboardsDir    string        `yaml:"dashboards_dir" env:"DASHBOARDS_DIR" reload:"restart" default:"/tmp/grafana_dashboards" desc:"directory dashboards are mirrored to when dashboards_mirror is enabled"`
+	DashboardsMirror bool          `yaml:"dashboards_mirror" env:"DASHBOARDS_MIRROR" desc:"also write dashboards to dashboards_dir, e.g. for Grafana file provisioning"`
+	PollInterval     time.Duration `yaml:"poll_interval" env:"POLL_INTERVAL_SEC" default:"60s" desc:"Git polling interval"`
+	GrafanaURL       string        `yaml:"grafana_url" env:"GRAFANA_URL" reload:"restart" desc:"Grafana URL"`
+	GrafanaUser      string        `yaml:"grafana_user" env:"GF_SECURITY_ADMIN_USER" desc:"Grafana admin user"`
+	GrafanaPass      string        `yaml:"grafana_password" env:"GF_SECURITY_ADMIN_PASSWORD" secret:"true" desc:"Grafana admin password"`
+	GrafanaToken     string        `yaml:"grafana_token" env:"GF_SECURITY_TOKEN" secret:"true" desc:"Grafana service account token"`
+	Include          []string      `yaml:"include" env:"INCLUDE_PATTERNS" desc:"gitignore-style patterns of repository paths to sync, comma-separated (default all)"`
+	Exclude          []string      `yaml:"exclude" env:"EXCLUDE_PATTERNS" desc:"gitignore-style patterns of repository paths to skip, comma-separated"`
+	GenerateUIDs     bool          `yaml:"generate_uids" env:"GENERATE_DASHBOARD_UIDS" reload:"restart" desc:"give dashboards without a uid a stable one derived from their repository path"`
+	UploadWorkers    int           `yaml:"upload_workers" env:"UPLOAD_WORKERS" default:"4" desc:"number of concurrent dashboard uploads"`
+	UploadRate       float64       `yaml:"upload_rate_limit" env:"UPLOAD_RATE_LIMIT" default:"10" desc:"maximum dashboard uploads per second, 0 for unlimited"`
+	ShutdownGrace    time.Duration `yaml:"shutdown_grace_period" env:"SHUTDOWN_GRACE_PERIOD_SEC" default:"30s" desc:"time an in-flight sync may keep running after SIGTERM"`
+
+	Annotations       string   `yaml:"annotations" env:"GRAFANA_ANNOTATIONS" default:"off" desc:"mark each sync that updates dashboards with a Grafana annotation: off, global (one organization-wide annotation) or dashboard (one on each updated dashboard)"`
+	AnnotationTags    []string `yaml:"annotation_tags" env:"GRAFANA_ANNOTATION_TAGS" default:"grafana-git-sync" desc:"comma-separated tags of the annotations, used to filter them in dashboards"`
+	CommitURLTemplate string   `yaml:"commit_url_template" env:"GIT_COMMIT_URL_TEMPLATE" desc:"link to a commit in the Git web UI, with {commit} replaced by the commit hash (default derived from the repository URL)"`
+
+	NotifySlackURL        string `yaml:"notify_slack_webhook_url" env:"NOTIFY_SLACK_WEBHOOK_URL" secret:"true" desc:"Slack incoming webhook URL for sync notifications"`
+	NotifyTeamsURL        string `yaml:"notify_teams_webhook_url" env:"NOTIFY_TEAMS_WEBHOOK_URL" secret:"true" desc:"Microsoft Teams workflow webhook URL for sync notifications"`
+	NotifyWebhookURL      string `yaml:"notify_webhook_url" env:"NOTIFY_WEBHOOK_URL" secret:"true" desc:"URL sync notifications are POSTed to as JSON"`
+	NotifyWebhookTemplate string `yaml:"notify_webhook_template" env:"NOTIFY_WEBHOOK_TEMPLATE" desc:"Go template of the webhook payload, rendered with the notification event (default the event as JSON)"`
+	NotifyOn              string `yaml:"notify_on" env:"NOTIFY_ON" default:"all" desc:"which notifications are sent: all (a summary of every synced commit), failure (summaries of failed syncs) or alert (only consecutive-failure alerts and recoveries)"`
+	NotifyAlertAfter      int    `yaml:"notify_alert_after" env:"NOTIFY_ALERT_AFTER" default:"3" desc:"alert after this many consecutive failed sync runs, 0 to disable"`
+
+	CommitStatus          string `yaml:"commit_status" env:"COMMIT_STATUS" reload:"restart" default:"off" desc:"report sync results as commit statuses: off, github, gitlab or gitea"`
+	CommitStatusAPIURL    string `yaml:"commit_status_api_url" env:"COMMIT_STATUS_API_URL" reload:"restart" desc:"forge API base URL (default derived from the repository URL, e.g. https://api.github.com or https://<host>/api/v4 for GitLab)"`
+	CommitStatusToken     string `yaml:"commit_status_token" env:"COMMIT_STATUS_TOKEN" secret:"true" reload:"restart" desc:"token allowed to set commit statuses (default the GitHub App installation token)"`
+	CommitStatusContext   string `yaml:"commit_status_context" env:"COMMIT_STATUS_CONTEXT" reload:"restart" default:"grafana-git-sync" desc:"name of the commit status"`
+	CommitStatusTargetURL string `yaml:"commit_status_target_url" env:"COMMIT_STATUS_TARGET_URL" reload:"restart" desc:"link shown with commit statuses, e.g. the externally reachable /status/resources endpoint"`
+
+	BackupDir       string `yaml:"backup_dir" env:"BACKUP_DIR" reload:"restart" desc:"directory the current Grafana state of each dashboard is saved to before a sync overwrites it, one timestamped backup per sync; disabled when empty"`
+	BackupRetention int    `yaml:"backup_retention" env:"BACKUP_RETENTION" reload:"restart" default:"10" desc:"number of backups kept in backup_dir, 0 keeps all"`
+
+	SafetyMaxDeletions     int     `yaml:"safety_max_deletions" env:"SAFETY_MAX_DELETIONS" desc:"block a sync when more than this many dashboard files were removed from the repository since the last sync, 0 to disable"`
+	SafetyMaxChangePercent float64 `yaml:"safety_max_change_percent" env:"SAFETY_MAX_CHANGE_PERCENT" desc:"block a sync that changes or removes more than this percentage of the dashboards of the last sync, 0 to disable"`
+	SafetyOverrideMarker   string  `yaml:"safety_override_marker" env:"SAFETY_OVERRIDE_MARKER" default:"[sync-override]" desc:"text in a commit message that lets its sync bypass the safety thresholds, disabled when empty"`
+	StateFile              string  `yaml:"state_file" env:"SYNC_STATE_FILE" reload:"restart" desc:"file that keeps the last synced commit and file hashes across restarts, so the safety thresholds also check the first sync after a start"`
+
+	HealthAddr            string        `yaml:"health_listen_addr" env:"HEALTH_LISTEN_ADDR" reload:"restart" desc:"health check listen address (default :$HEALTH_CHECK_PORT or :8080)"`
+	HealthStaleAfterPolls int           `yaml:"health_stale_after_polls" env:"HEALTH_STALE_AFTER_POLLS" default:"10" desc:"report unhealthy after this many polls without a successful sync, 0 to disable"`
+	HealthLivenessTimeout time.Duration `yaml:"health_liveness_timeout" env:"HEALTH_LIVENESS_TIMEOUT_SEC" desc:"fail /livez after this long without a sync loop heartbeat (default 3 poll intervals, at least 5m)"`
+
+	TLSCAFile             string `yaml:"tls_ca_file" env:"TLS_CA_FILE" reload:"restart" desc:"PEM bundle of certificate authorities trusted for Git over HTTPS and Grafana, in addition to the system roots"`
+	TLSCertFile           string `yaml:"tls_cert_file" env:"TLS_CERT_FILE" reload:"restart" desc:"client certificate for mutual TLS"`
+	TLSKeyFile            string `yaml:"tls_key_file" env:"TLS_KEY_FILE" reload:"restart" desc:"private key of the client certificate"`
+	TLSInsecureSkipVerify bool   `yaml:"tls_insecure_skip_verify" env:"TLS_INSECURE_SKIP_VERIFY" reload:"restart" desc:"skip server certificate verification (insecure, for testing only)"`
+	HTTPProxy             string `yaml:"http_proxy" env:"HTTP_PROXY" reload:"restart" desc:"proxy for http:// requests (default the http_proxy environment variable)"`
+	HTTPSProxy            string `yaml:"https_proxy" env:"HTTPS_PROXY" reload:"restart" desc:"proxy for https:// requests (default the https_proxy environment variable)"`
+	NoProxy               string `yaml:"no_proxy" env:"NO_PROXY" reload:"restart" desc:"comma-separated hosts, domains and CIDRs reached without a proxy"`
+
+	LogLevel  string `yaml:"log_level" env:"LOG_LEVEL" default:"info" desc:"minimum log level: debug, info, warn or error"`
+	LogFormat string `yaml:"log_format" env:"LOG_FORMAT" reload:"restart" default:"pretty" desc:"log format: pretty (console with emoji), text (logfmt) or json"`
+
+	TracesExporter string `yaml:"traces_exporter" env:"OTEL_TRACES_EXPORTER" reload:"restart" default:"none" desc:"OpenTelemetry trace exporter: none, otlp (configured by the OTEL_EXPORTER_OTLP_* variables) or console"`
+
+	AdminToken string `yaml:"admin_token" env:"ADMIN_TOKEN" secret:"true" reload:"restart" desc:"bearer token for the admin API, disabled when empty"`
+
+	SecretsRefreshInterval time.Duration `yaml:"secrets_refresh_interval" env:"SECRETS_REFRESH_INTERVAL_SEC" reload:"restart" default:"30s" desc:"how often secret files are checked for rotation"`
+	ConfigReloadInterval   time.Duration `yaml:"config_reload_interval" env:"CONFIG_RELOAD_INTERVAL_SEC" reload:"restart" default:"10s" desc:"how often the config file is checked for changes, 0 to reload on SIGHUP only"`
+
+	// ConfigFile is the config file the settings were loaded from, if any
+	ConfigFile string `yaml:"-"`
+
+	// SecretRefs maps secret field names to the reference they were read from
+	// (set through the *_FILE variants), so rotated values can be re-read
+	SecretRefs map[string]string `yaml:"-"`
+}
+
+// Load reads and validates configuration from the config file named by
+// CONFIG_FILE (if any) and environment variables
 func Load() (*Config, error) {
-	cfg := &Config{
-		RepoURL:       getEnvRequired("GIT_REPO_URL"),
-		Branch:        getEnvRequired("GIT_BRANCH"),
-		SSHKey:        os.Getenv("GIT_SSH_KEY"),
-		HTTPSUser:     os.Getenv("GIT_HTTPS_USER"),
-		HTTPSPassword: os.Getenv("GIT_HTTPS_PASS"),
-		RepoDir:       getEnv("GIT_LOCAL_REPO_DIR", "/tmp/grafana_data"),
-		RepoSubdir:    getEnv("GIT_REPO_SUBDIR", ""),
-		DashboardsDir: getEnv("DASHBOARDS_DIR", "/tmp/grafana_data"),
-		GrafanaURL:    getEnvRequired("GRAFANA_URL"),
-		GrafanaUser:   getEnv("GF_SECURITY_ADMIN_USER", ""),
-		GrafanaPass:   getEnv("GF_SECURITY_ADMIN_PASSWORD", ""),
-		GrafanaToken:  getEnv("GF_SECURITY_TOKEN", ""),
-	}
-
-	pollIntervalStr := getEnv("POLL_INTERVAL_SEC", "60")
-	pollIntervalSec, err := strconv.Atoi(pollIntervalStr)
-	if err != nil || pollIntervalSec <= 0 {
-		return nil, fmt.Errorf("invalid POLL_INTERVAL_SEC value: %s", pollIntervalStr)
-	}
-	cfg.PollInterval = time.Duration(pollIntervalSec) * time.Second
+	return LoadWithArgs(nil)
+}
+
+// LoadWithArgs reads and validates configuration, applying command-line flags
+// from args on top of environment variables, the config file and defaults.
+// The config file is taken from --config or CONFIG_FILE.
+func LoadWithArgs(args []string) (*Config, error) {
+	cfg := &Config{SecretRefs: make(map[string]string)}
+	set := make(map[string]bool)
+
+	fs, flagValues := newFlagSet()
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML config file")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := applyDefaults(cfg); err != nil {
+		return nil, err
+	}
+	if *configFile != "" {
+		if err := applyFile(cfg, *configFile, set); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(cfg, set); err != nil {
+		return nil, err
+	}
+
+	if err := applyFlags(cfg, fs, flagValues, set); err != nil {
+		return nil, err
+	}
+	if err := cfg.resolveSecrets(); err != nil {
+		return nil, err
+	}
+
+	cfg.applyDerivedDefaults(set)
+	cfg.ConfigFile = *configFile
 
 	if err := cfg.validate(); err != nil {
 		return nil, err
@@ -58,13 +174,174 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
-// validate checks that the configuration has valid authentication
+// applyDerivedDefaults fills in defaults that depend on other settings
+func (c *Config) applyDerivedDefaults(set map[string]bool) {
+	if c.HealthAddr == "" {
+		port := os.Getenv("HEALTH_CHECK_PORT")
+		if port == "" {
+			port = "8080"
+		}
+		c.HealthAddr = ":" + port
+	}
+
+	// By default the sync loop may miss three polls, but never less than five minutes
+	if !set["HealthLivenessTimeout"] {
+		c.HealthLivenessTimeout = 3 * c.PollInterval
+		if c.HealthLivenessTimeout < 5*time.Minute {
+			c.HealthLivenessTimeout = 5 * time.Minute
+		}
+	}
+}
+
+// resolveSecrets reads every secret given by reference into its field
+func (c *Config) resolveSecrets() error {
+	v := reflect.ValueOf(c).Elem()
+	for name, ref := range c.SecretRefs {
+		value, err := secrets.Resolve(context.Background(), ref)
+		if err != nil {
+			f, _ := reflect.TypeOf(Config{}).FieldByName(name)
+			return fmt.Errorf("failed to read %s_FILE: %w", f.Tag.Get("env"), err)
+		}
+		v.FieldByName(name).SetString(value)
+	}
+	return nil
+}
+
+// validate checks required settings, value ranges and authentication
 func (c *Config) validate() error {
-	// Check Git authentication
-	hasSSH := c.SSHKey != ""
-	hasHTTPS := c.HTTPSUser != "" && c.HTTPSPassword != ""
-	if !hasSSH && !hasHTTPS {
-		return fmt.Errorf("no Git authentication provided (SSH key or HTTPS credentials required)")
+	var missing []string
+	if c.RepoURL == "" {
+		missing = append(missing, "GIT_REPO_URL")
+	}
+	if c.Branch == "" && c.Ref == "" {
+		missing = append(missing, "GIT_BRANCH or GIT_REF")
+	}
+	if c.GrafanaURL == "" {
+		missing = append(missing, "GRAFANA_URL")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("required setting(s) not set: %s", strings.Join(missing, ", "))
+	}
+
+	if c.PollInterval < time.Second {
+		return fmt.Errorf("poll interval must be at least 1s, got %s", c.PollInterval)
+	}
+	if c.UploadWorkers < 0 || c.UploadRate < 0 || c.ShutdownGrace < 0 ||
+		c.HealthStaleAfterPolls < 0 || c.HealthLivenessTimeout < 0 {
+		return fmt.Errorf("numeric settings must not be negative")
+	}
+	// The loop only reports a heartbeat once per poll, so leave room for a slow run
+	if c.HealthLivenessTimeout > 0 && c.HealthLivenessTimeout < 2*c.PollInterval {
+		return fmt.Errorf("HEALTH_LIVENESS_TIMEOUT_SEC (%s) must be at least twice the poll interval (%s)", c.HealthLivenessTimeout, c.PollInterval)
+	}
+	if err := sync.ValidateRate(c.UploadRate); err != nil {
+		return fmt.Errorf("invalid UPLOAD_RATE_LIMIT: %w", err)
+	}
+	if c.DashboardsMirror && overlaps(c.RepoDir, c.DashboardsDir) {
+		return fmt.Errorf("DASHBOARDS_DIR (%s) must not overlap GIT_LOCAL_REPO_DIR (%s) when the mirror is enabled", c.DashboardsDir, c.RepoDir)
+	}
+	if _, err := c.RefSelector(); err != nil {
+		return fmt.Errorf("invalid GIT_REF: %w", err)
+	}
+	mode, err := git.ParseVerifyMode(c.VerifySignatures)
+	if err != nil {
+		return fmt.Errorf("invalid GIT_VERIFY_SIGNATURES: %w", err)
+	}
+	if mode != git.VerifyOff && c.TrustedKeys == "" {
+		return fmt.Errorf("GIT_TRUSTED_KEYS is required when GIT_VERIFY_SIGNATURES is %s", mode)
+	}
+	if _, err := sync.ParseSources(c.RepoSubdir); err != nil {
+		return fmt.Errorf("invalid GIT_REPO_SUBDIR: %w", err)
+	}
+
+	// Check Git authentication; HTTPS repositories may be public
+	isSSH := git.IsSSHURL(c.RepoURL)
+	if isSSH && c.SSHKey == "" && !c.SSHAgent {
+		return fmt.Errorf("no Git authentication provided (GIT_SSH_KEY or GIT_SSH_AGENT required for %s)", c.RepoURL)
+	}
+	if c.SSHKey != "" && c.SSHAgent {
+		return fmt.Errorf("conflicting SSH authentication (set either GIT_SSH_KEY or GIT_SSH_AGENT)")
+	}
+	if !isSSH && (c.SSHKey != "" || c.SSHAgent) {
+		return fmt.Errorf("SSH authentication is configured but GIT_REPO_URL %s is not an SSH URL (use ssh://[user@]host[:port]/path or [user@]host:path)", c.RepoURL)
+	}
+	if !isSSH && (c.HTTPSUser == "") != (c.HTTPSPassword == "") {
+		return fmt.Errorf("incomplete Git HTTPS credentials (both GIT_HTTPS_USER and GIT_HTTPS_PASS are required)")
+	}
+	hasApp := c.GitHubAppID != 0 || c.GitHubInstallID != 0 || c.GitHubAppKey != ""
+	if hasApp && (c.GitHubAppID <= 0 || c.GitHubInstallID <= 0 || c.GitHubAppKey == "") {
+		return fmt.Errorf("incomplete GitHub App credentials (GITHUB_APP_ID, GITHUB_APP_INSTALLATION_ID and GITHUB_APP_PRIVATE_KEY are required)")
+	}
+	if isSSH && (hasApp || c.HTTPSToken != "") {
+		return fmt.Errorf("GitHub App and GIT_HTTPS_TOKEN authentication require an HTTPS repository URL")
+	}
+	httpsMethods := 0
+	for _, set := range []bool{hasApp, c.HTTPSToken != "", c.HTTPSPassword != ""} {
+		if set {
+			httpsMethods++
+		}
+	}
+	if !isSSH && httpsMethods > 1 {
+		return fmt.Errorf("conflicting Git HTTPS credentials (set only one of GitHub App, GIT_HTTPS_TOKEN or GIT_HTTPS_USER/GIT_HTTPS_PASS)")
+	}
+
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		return err
+	}
+	if !logging.ValidFormat(c.LogFormat) {
+		return fmt.Errorf("invalid LOG_FORMAT %q (want pretty, text or json)", c.LogFormat)
+	}
+
+	if !tracing.ValidExporter(c.TracesExporter) {
+		return fmt.Errorf("invalid OTEL_TRACES_EXPORTER %q (want none, otlp or console)", c.TracesExporter)
+	}
+
+	if _, err := grafana.ParseAnnotationMode(c.Annotations); err != nil {
+		return fmt.Errorf("invalid GRAFANA_ANNOTATIONS: %w", err)
+	}
+
+	if !notify.ValidOn(c.NotifyOn) {
+		return fmt.Errorf("invalid NOTIFY_ON %q (want all, failure or alert)", c.NotifyOn)
+	}
+	if c.NotifyAlertAfter < 0 {
+		return fmt.Errorf("NOTIFY_ALERT_AFTER must not be negative")
+	}
+	if _, err := c.NotifyOptions().Notifiers(nil); err != nil {
+		return fmt.Errorf("invalid NOTIFY_WEBHOOK_TEMPLATE: %w", err)
+	}
+
+	kind, err := forge.ParseKind(c.CommitStatus)
+	if err != nil {
+		return fmt.Errorf("invalid COMMIT_STATUS: %w", err)
+	}
+	if kind != forge.KindOff {
+		if _, _, ok := git.RepoWebURL(c.RepoURL); !ok {
+			return fmt.Errorf("COMMIT_STATUS requires a remote GIT_REPO_URL")
+		}
+		if c.CommitStatusToken == "" && (kind != forge.KindGitHub || !hasApp) {
+			return fmt.Errorf("COMMIT_STATUS_TOKEN is required when COMMIT_STATUS is %s", kind)
+		}
+	}
+
+	if c.BackupRetention < 0 {
+		return fmt.Errorf("BACKUP_RETENTION must not be negative")
+	}
+	if c.BackupDir != "" && (overlaps(c.BackupDir, c.RepoDir) || (c.DashboardsMirror && overlaps(c.BackupDir, c.DashboardsDir))) {
+		return fmt.Errorf("BACKUP_DIR (%s) must not overlap GIT_LOCAL_REPO_DIR or the dashboard mirror", c.BackupDir)
+	}
+
+	if c.SafetyMaxDeletions < 0 {
+		return fmt.Errorf("SAFETY_MAX_DELETIONS must not be negative")
+	}
+	if c.SafetyMaxChangePercent < 0 || c.SafetyMaxChangePercent > 100 {
+		return fmt.Errorf("SAFETY_MAX_CHANGE_PERCENT must be between 0 and 100")
+	}
+	if c.StateFile != "" && overlaps(c.StateFile, c.RepoDir) {
+		return fmt.Errorf("SYNC_STATE_FILE (%s) must not be inside GIT_LOCAL_REPO_DIR, which is recreated on every start", c.StateFile)
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
 	}
 
 	// Check Grafana authentication
@@ -77,26 +354,322 @@ func (c *Config) validate() error {
 	return nil
 }
 
+// GitCredentials returns the credentials used to access the repository
+func (c *Config) GitCredentials() git.Credentials {
+	return git.Credentials{
+		SSHKey:               c.SSHKey,
+		SSHKeyPassphrase:     c.SSHKeyPassphrase,
+		SSHAgent:             c.SSHAgent,
+		SSHUser:              c.SSHUser,
+		HTTPSUser:            c.HTTPSUser,
+		HTTPSPassword:        c.HTTPSPassword,
+		HTTPSToken:           c.HTTPSToken,
+		GitHubAppID:          int64(c.GitHubAppID),
+		GitHubInstallationID: int64(c.GitHubInstallID),
+		GitHubAppKey:         c.GitHubAppKey,
+		GitHubAPIURL:         c.GitHubAPIURL,
+	}
+}
+
+// HTTPOptions returns the TLS and proxy settings for Git over HTTPS and Grafana
+func (c *Config) HTTPOptions() httpclient.Options {
+	return httpclient.Options{
+		CAFile:             c.TLSCAFile,
+		CertFile:           c.TLSCertFile,
+		KeyFile:            c.TLSKeyFile,
+		InsecureSkipVerify: c.TLSInsecureSkipVerify,
+		HTTPProxy:          c.HTTPProxy,
+		HTTPSProxy:         c.HTTPSProxy,
+		NoProxy:            c.NoProxy,
+	}
+}
+
+// NotifyOptions returns the sync notification settings
+func (c *Config) NotifyOptions() notify.Options {
+	return notify.Options{
+		SlackURL:        c.NotifySlackURL,
+		TeamsURL:        c.NotifyTeamsURL,
+		WebhookURL:      c.NotifyWebhookURL,
+		WebhookTemplate: c.NotifyWebhookTemplate,
+		On:              c.NotifyOn,
+		AlertAfter:      c.NotifyAlertAfter,
+	}
+}
+
+// SafetyThresholds returns the limits that block a sync from applying mass changes
+func (c *Config) SafetyThresholds() sync.Thresholds {
+	return sync.Thresholds{
+		MaxDeletions:     c.SafetyMaxDeletions,
+		MaxChangePercent: c.SafetyMaxChangePercent,
+	}
+}
+
+// RefSelector returns the revision to sync: GIT_REF if set, otherwise the head of GIT_BRANCH
+func (c *Config) RefSelector() (git.RefSelector, error) {
+	if c.Ref == "" {
+		return git.BranchRef(c.Branch), nil
+	}
+	return git.ParseRef(c.Ref)
+}
+
+// overlaps reports whether one directory is the same as or inside the other
+func overlaps(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	inside := func(dir, parent string) bool {
+		return dir == parent || strings.HasPrefix(dir, parent+string(filepath.Separator))
+	}
+	return inside(a, b) || inside(b, a)
+}
+
 // SafeForLog returns a copy of the config with sensitive fields masked
 func (c *Config) SafeForLog() *Config {
 	masked := maskSensitiveFields(c).(Config)
 	return &masked
 }
 
-func getEnv(key, defaultVal string) string {
-	val := os.Getenv(key)
-	if val == "" {
-		return defaultVal
+// Print writes the effective configuration, with sensitive fields masked, in config file format
+func (c *Config) Print(w io.Writer) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.SafeForLog()); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
 	}
-	return val
+	_, err := w.Write(buf.Bytes())
+	return err
 }
 
-func getEnvRequired(key string) string {
-	val := os.Getenv(key)
-	if val == "" {
-		log.Fatalf("❌ Required environment variable %s is not set", key)
+// fields returns the settable fields of Config with their tags
+func fields() []reflect.StructField {
+	t := reflect.TypeOf(Config{})
+	var result []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("yaml"); key != "" && key != "-" {
+			result = append(result, t.Field(i))
+		}
 	}
-	return val
+	return result
+}
+
+func applyDefaults(cfg *Config) error {
+	v := reflect.ValueOf(cfg).Elem()
+	for _, f := range fields() {
+		def, ok := f.Tag.Lookup("default")
+		if !ok {
+			continue
+		}
+		if err := setField(v.FieldByIndex(f.Index), def); err != nil {
+			return fmt.Errorf("invalid default for %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+func applyFile(cfg *Config, path string, set map[string]bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	byKey := make(map[string]reflect.StructField)
+	secretByKey := make(map[string]reflect.StructField)
+	for _, f := range fields() {
+		byKey[f.Tag.Get("yaml")] = f
+		if f.Tag.Get("secret") == "true" {
+			secretByKey[f.Tag.Get("yaml")+"_file"] = f
+		}
+	}
+
+	v := reflect.ValueOf(cfg).Elem()
+	for key, node := range doc {
+		if f, ok := secretByKey[key]; ok {
+			if _, both := doc[f.Tag.Get("yaml")]; both {
+				return fmt.Errorf("invalid config file %s: set only one of %s and %s", path, f.Tag.Get("yaml"), key)
+			}
+			cfg.SecretRefs[f.Name] = node.Value
+			set[f.Name] = true
+			continue
+		}
+
+		f, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("invalid config file %s: unknown setting %q", path, key)
+		}
+		delete(cfg.SecretRefs, f.Name)
+		field := v.FieldByIndex(f.Index)
+
+		// Scalars share the env parser so durations accept seconds or "5m" alike
+		if node.Kind == yaml.ScalarNode {
+			err = setField(field, node.Value)
+		} else {
+			err = node.Decode(field.Addr().Interface())
+		}
+		if err != nil {
+			return fmt.Errorf("invalid config file %s: %s: %w", path, key, err)
+		}
+		set[f.Name] = true
+	}
+	return nil
+}
+
+func applyEnv(cfg *Config, set map[string]bool) error {
+	v := reflect.ValueOf(cfg).Elem()
+	for _, f := range fields() {
+		key := f.Tag.Get("env")
+		if key == "" {
+			continue
+		}
+		val := os.Getenv(key)
+		if f.Tag.Get("secret") == "true" {
+			if ref := os.Getenv(key + "_FILE"); ref != "" {
+				if val != "" {
+					return fmt.Errorf("set only one of %s and %s_FILE", key, key)
+				}
+				cfg.SecretRefs[f.Name] = ref
+				set[f.Name] = true
+				continue
+			}
+		}
+		if val == "" {
+			continue
+		}
+		if err := setField(v.FieldByIndex(f.Index), val); err != nil {
+			return fmt.Errorf("invalid %s value: %s", key, val)
+		}
+		delete(cfg.SecretRefs, f.Name)
+		set[f.Name] = true
+	}
+	return nil
+}
+
+func applyFlags(cfg *Config, fs *flag.FlagSet, values map[string]*flagValue, set map[string]bool) error {
+	v := reflect.ValueOf(cfg).Elem()
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		fv, ok := values[f.Name]
+		if !ok || err != nil {
+			return
+		}
+		set[fv.field] = true
+		if fv.secretRef {
+			cfg.SecretRefs[fv.field] = fv.raw
+			return
+		}
+		field, _ := reflect.TypeOf(Config{}).FieldByName(fv.field)
+		if setErr := setField(v.FieldByIndex(field.Index), fv.raw); setErr != nil {
+			err = fmt.Errorf("invalid --%s value: %s", f.Name, fv.raw)
+			return
+		}
+		delete(cfg.SecretRefs, fv.field)
+	})
+	return err
+}
+
+// flagValue records a command-line flag; it is applied after the other layers
+type flagValue struct {
+	field     string
+	raw       string
+	secretRef bool // the flag names a secret reference rather than the value
+	isBool    bool // the flag may be given without a value
+}
+
+func (f *flagValue) String() string { return f.raw }
+
+// IsBoolFlag lets boolean settings be enabled with a bare --flag
+func (f *flagValue) IsBoolFlag() bool { return f.isBool }
+
+func (f *flagValue) Set(s string) error {
+	f.raw = s
+	return nil
+}
+
+func newFlagSet() (*flag.FlagSet, map[string]*flagValue) {
+	fs := flag.NewFlagSet("grafana-git-sync", flag.ContinueOnError)
+	values := make(map[string]*flagValue)
+
+	for _, f := range fields() {
+		name := strings.ReplaceAll(f.Tag.Get("yaml"), "_", "-")
+		fv := &flagValue{field: f.Name, isBool: f.Type.Kind() == reflect.Bool}
+		usage := f.Tag.Get("desc")
+		if env := f.Tag.Get("env"); env != "" {
+			usage += " ($" + env + ")"
+		}
+		fs.Var(fv, name, usage)
+		values[name] = fv
+
+		if f.Tag.Get("secret") == "true" {
+			ref := &flagValue{field: f.Name, secretRef: true}
+			fs.Var(ref, name+"-file", "file to read "+name+" from")
+			values[name+"-file"] = ref
+		}
+	}
+	return fs, values
+}
+
+// setField parses s into v according to the field type. Durations accept
+// either a number of seconds or a Go duration string such as "90s" or "5m".
+func setField(v reflect.Value, s string) error {
+	switch v.Interface().(type) {
+	case string:
+		v.SetString(s)
+	case time.Duration:
+		if secs, err := strconv.Atoi(s); err == nil {
+			v.SetInt(int64(time.Duration(secs) * time.Second))
+			return nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case []string:
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	case map[string]string:
+		m := make(map[string]string)
+		for _, pair := range strings.Split(s, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("expected key=value, got %q", pair)
+			}
+			m[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
 }
 
 func maskSensitiveFields(input any) any {
@@ -123,8 +696,9 @@ func maskSensitiveFields(input any) any {
 		field := t.Field(i)
 		value := v.Field(i)
 
+		// Fields tagged secret are always masked, the name patterns catch the rest
 		nameLower := strings.ToLower(field.Name)
-		isSensitive := false
+		isSensitive := field.Tag.Get("secret") == "true"
 		for _, p := range sensitivePatterns {
 			if strings.Contains(nameLower, p) {
 				isSensitive = true
@@ -138,6 +712,8 @@ func maskSensitiveFields(input any) any {
 			} else {
 				masked.Field(i).Set(value)
 			}
+		} else if value.Kind() == reflect.String {
+			masked.Field(i).SetString(maskURLPassword(value.String()))
 		} else {
 			masked.Field(i).Set(value)
 		}
@@ -145,3 +721,16 @@ func maskSensitiveFields(input any) any {
 
 	return masked.Interface()
 }
+
+// maskURLPassword hides the password of URLs with embedded credentials
+func maskURLPassword(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	password, hasPassword := u.User.Password()
+	if !hasPassword || password == "" {
+		return s
+	}
+	return strings.Replace(s, ":"+password+"@", ":***@", 1)
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Change describes a setting that differs between two configurations
+type Change struct {
+	Key     string // config file key, e.g. poll_interval
+	Restart bool   // the new value only takes effect after a restart
+}
+
+// Reload merges a freshly loaded configuration into the current one. It returns
+// the configuration to run with, which keeps the current value of every setting
+// that needs a restart, and the list of settings that changed.
+func Reload(current, next *Config) (*Config, []Change) {
+	applied := *next
+	cur := reflect.ValueOf(current).Elem()
+	app := reflect.ValueOf(&applied).Elem()
+
+	var changes []Change
+	for _, f := range fields() {
+		old := cur.FieldByIndex(f.Index)
+		if reflect.DeepEqual(old.Interface(), app.FieldByIndex(f.Index).Interface()) {
+			continue
+		}
+		restart := f.Tag.Get("reload") == "restart"
+		if restart {
+			app.FieldByIndex(f.Index).Set(old)
+		}
+		changes = append(changes, Change{Key: f.Tag.Get("yaml"), Restart: restart})
+	}
+	if applied.ConfigFile != current.ConfigFile {
+		applied.ConfigFile = current.ConfigFile
+		changes = append(changes, Change{Key: "config", Restart: true})
+	}
+	return &applied, changes
+}
+
+// WatchFile calls onChange whenever the content of path changes, checking every
+// interval until ctx is cancelled. A missing file counts as empty content.
+func WatchFile(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := fileHash(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if hash := fileHash(path); hash != last {
+				last = hash
+				slog.Info("Config file changed", "path", path)
+				onChange()
+			}
+		}
+	}
+}
+
+func fileHash(path string) [sha256.Size]byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}
+	}
+	return sha256.Sum256(data)
+}
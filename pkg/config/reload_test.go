@@ -0,0 +1,68 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	current := &Config{
+		RepoURL:       "https://github.com/test/repo.git",
+		GrafanaURL:    "http://localhost:3000",
+		PollInterval:  time.Minute,
+		UploadWorkers: 4,
+	}
+	next := *current
+	next.RepoURL = "https://github.com/test/other.git"
+	next.PollInterval = 30 * time.Second
+
+	applied, changes := Reload(current, &next)
+
+	if applied.PollInterval != 30*time.Second {
+		t.Errorf("PollInterval = %v, want reloaded 30s", applied.PollInterval)
+	}
+	if applied.RepoURL != current.RepoURL {
+		t.Errorf("RepoURL = %s, want unchanged until restart", applied.RepoURL)
+	}
+
+	want := map[string]bool{"repo_url": true, "poll_interval": false}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %+v, want %d entries", changes, len(want))
+	}
+	for _, c := range changes {
+		restart, ok := want[c.Key]
+		if !ok || c.Restart != restart {
+			t.Errorf("unexpected change %+v", c)
+		}
+	}
+
+	if _, changes := Reload(current, current); len(changes) != 0 {
+		t.Errorf("Reload() of identical config reported %+v", changes)
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("poll_interval: 1m\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go WatchFile(ctx, path, 10*time.Millisecond, func() { changed <- struct{}{} })
+
+	time.Sleep(30 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("poll_interval: 2m\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("WatchFile() did not report the change")
+	}
+}
@@ -0,0 +1,228 @@
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	gosync "sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"grafana_git_sync/pkg/git"
+	"grafana_git_sync/pkg/tracing"
+)
+
+var tracer = otel.Tracer("grafana_git_sync/pkg/forge")
+
+// Kind is a Git forge whose commit status API is supported
+type Kind string
+
+const (
+	KindOff    Kind = "off"
+	KindGitHub Kind = "github"
+	KindGitLab Kind = "gitlab"
+	KindGitea  Kind = "gitea" // also Forgejo
+)
+
+// ParseKind parses a Kind; an empty string is KindOff
+func ParseKind(s string) (Kind, error) {
+	switch kind := Kind(strings.ToLower(strings.TrimSpace(s))); kind {
+	case "", KindOff:
+		return KindOff, nil
+	case KindGitHub, KindGitLab, KindGitea:
+		return kind, nil
+	default:
+		return "", fmt.Errorf("unknown forge %q (want off, github, gitlab or gitea)", s)
+	}
+}
+
+// State is the result reported for a commit
+type State string
+
+const (
+	StateSuccess State = "success"
+	StateFailure State = "failure"
+)
+
+// maxDescription is the longest description GitHub accepts, in characters
+const maxDescription = 140
+
+// truncateDescription shortens s to maxDescription characters, cutting only
+// between characters so multi-byte paths stay valid UTF-8
+func truncateDescription(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxDescription {
+		return s
+	}
+	return string(runes[:maxDescription-3]) + "..."
+}
+
+// Status is reported for a commit
+type Status struct {
+	State       State
+	Description string // truncated to 140 characters
+	TargetURL   string // optional link shown next to the status
+}
+
+// TokenSource returns the token used to call the forge API
+type TokenSource func(ctx context.Context) (string, error)
+
+// StaticToken is a TokenSource for a fixed token
+func StaticToken(token string) TokenSource {
+	return func(context.Context) (string, error) { return token, nil }
+}
+
+// Reporter posts commit statuses for one repository
+type Reporter struct {
+	kind    Kind
+	apiURL  string
+	project string // owner/repo, or the URL-encoded project path for GitLab
+	name    string // status context
+	token   TokenSource
+	client  *http.Client
+
+	mu   gosync.Mutex
+	last map[string]Status // last status per commit, to skip repeats
+}
+
+// NewReporter creates a Reporter for the repository at repoURL. An empty
+// apiURL is derived from the repository host: https://api.github.com for
+// github.com, https://<host>/api/v3 for GitHub Enterprise Server,
+// https://<host>/api/v4 for GitLab and https://<host>/api/v1 for Gitea.
+func NewReporter(kind Kind, apiURL, repoURL, name string, token TokenSource, client *http.Client) (*Reporter, error) {
+	base, path, ok := git.RepoWebURL(repoURL)
+	if !ok {
+		return nil, fmt.Errorf("cannot determine the repository path from %s", repoURL)
+	}
+	if apiURL == "" {
+		apiURL = DefaultAPIURL(kind, base)
+	}
+	project := path
+	if kind == KindGitLab {
+		project = url.PathEscape(path)
+	} else if strings.Count(path, "/") != 1 {
+		return nil, fmt.Errorf("repository path %s is not owner/repo", path)
+	}
+	return &Reporter{
+		kind:    kind,
+		apiURL:  strings.TrimRight(apiURL, "/"),
+		project: project,
+		name:    name,
+		token:   token,
+		client:  client,
+		last:    make(map[string]Status),
+	}, nil
+}
+
+// DefaultAPIURL returns the API base URL of the forge serving the web UI at base
+func DefaultAPIURL(kind Kind, base string) string {
+	switch kind {
+	case KindGitLab:
+		return base + "/api/v4"
+	case KindGitea:
+		return base + "/api/v1"
+	}
+	if base == "https://github.com" {
+		return "https://api.github.com"
+	}
+	return base + "/api/v3"
+}
+
+// SetStatus reports s for commit. A status identical to the last one
+// reported for the commit, e.g. while failed uploads are retried, is skipped.
+func (r *Reporter) SetStatus(ctx context.Context, commit string, s Status) error {
+	s.Description = truncateDescription(s.Description)
+	r.mu.Lock()
+	if r.last[commit] == s {
+		r.mu.Unlock()
+		return nil
+	}
+	r.mu.Unlock()
+
+	ctx, span := tracer.Start(ctx, "forge.commit_status", trace.WithAttributes(
+		tracing.Commit.String(commit),
+		attribute.String("forge.kind", string(r.kind)),
+		attribute.String("forge.state", string(s.State)),
+	))
+	defer span.End()
+
+	if err := r.post(ctx, commit, s); err != nil {
+		tracing.Fail(span, err)
+		return err
+	}
+	slog.Debug("Commit status reported", "commit", commit, "state", s.State)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Only the latest commit is retried, older ones need not be remembered
+	clear(r.last)
+	r.last[commit] = s
+	return nil
+}
+
+// post sends the status in the format of the forge API
+func (r *Reporter) post(ctx context.Context, commit string, s Status) error {
+	token, err := r.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	var endpoint string
+	body := map[string]string{
+		"state":       string(s.State),
+		"description": s.Description,
+	}
+	if s.TargetURL != "" {
+		body["target_url"] = s.TargetURL
+	}
+	switch r.kind {
+	case KindGitLab:
+		endpoint = fmt.Sprintf("%s/projects/%s/statuses/%s", r.apiURL, r.project, commit)
+		body["name"] = r.name
+		if s.State == StateFailure {
+			body["state"] = "failed"
+		}
+	default:
+		endpoint = fmt.Sprintf("%s/repos/%s/statuses/%s", r.apiURL, r.project, commit)
+		body["context"] = r.name
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch r.kind {
+	case KindGitHub:
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	case KindGitLab:
+		req.Header.Set("PRIVATE-TOKEN", token)
+	case KindGitea:
+		req.Header.Set("Authorization", "token "+token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s API error %d: %s", r.kind, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
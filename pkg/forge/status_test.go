@@ -0,0 +1,178 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParseKind(t *testing.T) {
+	tests := []struct {
+		kind    string
+		want    Kind
+		wantErr bool
+	}{
+		{kind: "", want: KindOff},
+		{kind: "GitHub", want: KindGitHub},
+		{kind: "gitlab", want: KindGitLab},
+		{kind: "gitea", want: KindGitea},
+		{kind: "bitbucket", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			got, err := ParseKind(tt.kind)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKind() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseKind() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultAPIURL(t *testing.T) {
+	tests := []struct {
+		kind Kind
+		base string
+		want string
+	}{
+		{kind: KindGitHub, base: "https://github.com", want: "https://api.github.com"},
+		{kind: KindGitHub, base: "https://github.example.com", want: "https://github.example.com/api/v3"},
+		{kind: KindGitLab, base: "https://gitlab.example.com", want: "https://gitlab.example.com/api/v4"},
+		{kind: KindGitea, base: "http://gitea.local:3000", want: "http://gitea.local:3000/api/v1"},
+	}
+
+	for _, tt := range tests {
+		if got := DefaultAPIURL(tt.kind, tt.base); got != tt.want {
+			t.Errorf("DefaultAPIURL(%s, %s) = %s, want %s", tt.kind, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestReporter_SetStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		kind       Kind
+		repoURL    string
+		wantPath   string
+		wantHeader [2]string
+		wantBody   map[string]string
+	}{
+		{
+			name:       "github",
+			kind:       KindGitHub,
+			repoURL:    "git@github.com:org/dashboards.git",
+			wantPath:   "/repos/org/dashboards/statuses/abc123",
+			wantHeader: [2]string{"Authorization", "Bearer secret"},
+			wantBody:   map[string]string{"state": "failure", "context": "grafana-git-sync", "target_url": "https://sync.example.com/status/resources"},
+		},
+		{
+			name:       "gitlab",
+			kind:       KindGitLab,
+			repoURL:    "https://gitlab.example.com/group/sub/dashboards.git",
+			wantPath:   "/projects/group%2Fsub%2Fdashboards/statuses/abc123",
+			wantHeader: [2]string{"PRIVATE-TOKEN", "secret"},
+			wantBody:   map[string]string{"state": "failed", "name": "grafana-git-sync"},
+		},
+		{
+			name:       "gitea",
+			kind:       KindGitea,
+			repoURL:    "https://gitea.example.com/org/dashboards",
+			wantPath:   "/repos/org/dashboards/statuses/abc123",
+			wantHeader: [2]string{"Authorization", "token secret"},
+			wantBody:   map[string]string{"state": "failure", "context": "grafana-git-sync"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if r.Method != "POST" || r.URL.EscapedPath() != tt.wantPath {
+					t.Errorf("request = %s %s, want POST %s", r.Method, r.URL.EscapedPath(), tt.wantPath)
+				}
+				if got := r.Header.Get(tt.wantHeader[0]); got != tt.wantHeader[1] {
+					t.Errorf("%s = %q, want %q", tt.wantHeader[0], got, tt.wantHeader[1])
+				}
+				var body map[string]string
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				for key, want := range tt.wantBody {
+					if body[key] != want {
+						t.Errorf("%s = %q, want %q", key, body[key], want)
+					}
+				}
+				if len(body["description"]) != maxDescription || !strings.HasSuffix(body["description"], "...") {
+					t.Errorf("description not truncated: %q", body["description"])
+				}
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer server.Close()
+
+			reporter, err := NewReporter(tt.kind, server.URL, tt.repoURL, "grafana-git-sync", StaticToken("secret"), server.Client())
+			if err != nil {
+				t.Fatal(err)
+			}
+			status := Status{
+				State:       StateFailure,
+				Description: "2 dashboards failed: " + strings.Repeat("teams/payments/latency.json, ", 10),
+				TargetURL:   tt.wantBody["target_url"],
+			}
+			for i := 0; i < 2; i++ {
+				if err := reporter.SetStatus(context.Background(), "abc123", status); err != nil {
+					t.Fatalf("SetStatus() error = %v", err)
+				}
+			}
+			if requests != 1 {
+				t.Errorf("%d requests sent, want 1 as the repeated status is skipped", requests)
+			}
+		})
+	}
+}
+
+func TestReporter_Errors(t *testing.T) {
+	if _, err := NewReporter(KindGitHub, "", "/srv/git/repo.git", "ci", StaticToken("t"), http.DefaultClient); err == nil {
+		t.Error("NewReporter() should reject a local repository")
+	}
+	if _, err := NewReporter(KindGitHub, "", "https://github.com/org/sub/repo.git", "ci", StaticToken("t"), http.DefaultClient); err == nil {
+		t.Error("NewReporter() should reject a path that is not owner/repo")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	reporter, err := NewReporter(KindGitHub, server.URL, "https://github.com/org/repo.git", "ci", StaticToken("t"), server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = reporter.SetStatus(context.Background(), "abc123", Status{State: StateSuccess})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("SetStatus() error = %v, want the 401 response", err)
+	}
+	// A failed report is retried
+	if err := reporter.SetStatus(context.Background(), "abc123", Status{State: StateSuccess}); err == nil {
+		t.Error("SetStatus() should retry a status that failed to post")
+	}
+}
+
+func TestTruncateDescription(t *testing.T) {
+	short := "Synced to Grafana: 1 created, 0 updated"
+	if got := truncateDescription(short); got != short {
+		t.Errorf("truncateDescription(%q) = %q, want it unchanged", short, got)
+	}
+
+	long := "2 dashboards failed: " + strings.Repeat("équipe/übersicht-📈.json, ", 10)
+	got := truncateDescription(long)
+	if !utf8.ValidString(got) || utf8.RuneCountInString(got) != maxDescription || !strings.HasSuffix(got, "...") {
+		t.Errorf("truncateDescription() = %q (%d characters), want %d valid characters", got, utf8.RuneCountInString(got), maxDescription)
+	}
+}
@@ -0,0 +1,189 @@
+package git
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	gosync "sync"
+	"time"
+)
+
+// DefaultGitHubAPIURL is the API of github.com
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// tokenRefreshMargin is how long before expiry an installation token is replaced
+const tokenRefreshMargin = 5 * time.Minute
+
+// GitHubApp mints short-lived installation access tokens for a GitHub App
+type GitHubApp struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	apiURL         string
+	client         *http.Client
+
+	mu      gosync.Mutex
+	token   string
+	expires time.Time
+	now     func() time.Time
+}
+
+// NewGitHubApp creates a token source for an App installation from the App's
+// PEM-encoded private key. apiURL defaults to DefaultGitHubAPIURL; GitHub
+// Enterprise Server uses https://<host>/api/v3.
+func NewGitHubApp(appID, installationID int64, privateKey, apiURL string) (*GitHubApp, error) {
+	if appID <= 0 || installationID <= 0 {
+		return nil, errors.New("GitHub App ID and installation ID are required")
+	}
+	key, err := parseRSAKey(strings.ReplaceAll(privateKey, `\n`, "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub App private key: %w", err)
+	}
+	if apiURL == "" {
+		apiURL = DefaultGitHubAPIURL
+	}
+
+	return &GitHubApp{
+		appID:          appID,
+		installationID: installationID,
+		key:            key,
+		apiURL:         strings.TrimSuffix(apiURL, "/"),
+		client:         &http.Client{Timeout: 30 * time.Second, Transport: currentTransport()},
+		now:            time.Now,
+	}, nil
+}
+
+func parseRSAKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// Token returns a valid installation token, minting a new one when the
+// current token is missing or about to expire
+func (a *GitHubApp) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && a.now().Before(a.expires.Add(-tokenRefreshMargin)) {
+		return a.token, nil
+	}
+
+	token, expires, err := a.mint(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create GitHub App installation token: %w", err)
+	}
+	a.token, a.expires = token, expires
+	slog.Info("Created GitHub App installation token", "expires", expires)
+	return token, nil
+}
+
+// mint exchanges an App JWT for an installation token
+func (a *GitHubApp) mint(ctx context.Context) (string, time.Time, error) {
+	jwt, err := a.jwt(a.now())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", a.apiURL, a.installationID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusCreated {
+		return "", time.Time{}, fmt.Errorf("status %d: %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if result.Token == "" {
+		return "", time.Time{}, errors.New("response contains no token")
+	}
+	return result.Token, result.ExpiresAt, nil
+}
+
+// jwt returns an RS256-signed JSON Web Token identifying the App. It is
+// backdated a minute to allow for clock drift and valid for the 10 minutes GitHub permits at most.
+func (a *GitHubApp) jwt(now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(a.appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// gitHubAppAuth authenticates Git HTTPS requests with the current installation token
+type gitHubAppAuth struct {
+	app *GitHubApp
+}
+
+func (a *gitHubAppAuth) Name() string { return "http-github-app" }
+
+func (a *gitHubAppAuth) String() string {
+	return fmt.Sprintf("%s - app %d, installation %d", a.Name(), a.app.appID, a.app.installationID)
+}
+
+// SetAuth adds the installation token as basic auth, which GitHub expects for Git over HTTPS
+func (a *gitHubAppAuth) SetAuth(r *http.Request) {
+	token, err := a.app.Token(r.Context())
+	if err != nil {
+		// The request is sent unauthenticated and fails with an authentication error
+		slog.Warn("Sending Git request without GitHub App token", "error", err)
+		return
+	}
+	r.SetBasicAuth("x-access-token", token)
+}
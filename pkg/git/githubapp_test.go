@@ -0,0 +1,126 @@
+package git
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGitHub serves installation tokens for installation 42 to App 7
+func fakeGitHub(t *testing.T, key *rsa.PrivateKey, minted *int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/42/access_tokens" {
+			http.NotFound(w, r)
+			return
+		}
+
+		// Check the App JWT like GitHub does
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		if len(parts) != 3 {
+			http.Error(w, "malformed JWT", http.StatusUnauthorized)
+			return
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		var claims struct {
+			Iss string `json:"iss"`
+			Iat int64  `json:"iat"`
+			Exp int64  `json:"exp"`
+		}
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		if err := json.Unmarshal(payload, &claims); err != nil || claims.Iss != "7" || claims.Exp-claims.Iat > 600 {
+			http.Error(w, "bad claims", http.StatusUnauthorized)
+			return
+		}
+
+		// Tokens live an hour from when the JWT was issued, which follows the App's clock
+		expires := time.Unix(claims.Iat, 0).Add(time.Minute + time.Hour)
+		*minted++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "ghs_%d", "expires_at": %q}`, *minted, expires.UTC().Format(time.RFC3339))
+	}))
+}
+
+func rsaKeyPEM(t *testing.T) (*rsa.PrivateKey, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+func TestGitHubApp_Token(t *testing.T) {
+	key, keyPEM := rsaKeyPEM(t)
+	var minted int
+	server := fakeGitHub(t, key, &minted)
+	defer server.Close()
+
+	app, err := NewGitHubApp(7, 42, keyPEM, server.URL)
+	if err != nil {
+		t.Fatalf("NewGitHubApp() error = %v", err)
+	}
+	now := time.Now()
+	app.now = func() time.Time { return now }
+
+	token, err := app.Token(context.Background())
+	if err != nil || token != "ghs_1" {
+		t.Fatalf("Token() = %q, %v", token, err)
+	}
+
+	// The token is reused until it is about to expire
+	now = now.Add(50 * time.Minute)
+	if token, _ := app.Token(context.Background()); token != "ghs_1" {
+		t.Errorf("Token() = %q, want the cached token", token)
+	}
+	now = now.Add(6 * time.Minute)
+	if token, _ := app.Token(context.Background()); token != "ghs_2" {
+		t.Errorf("Token() = %q, want a refreshed token", token)
+	}
+
+	req := httptest.NewRequest("GET", "https://github.com/org/dashboards.git/info/refs", nil)
+	(&gitHubAppAuth{app: app}).SetAuth(req)
+	if user, pass, ok := req.BasicAuth(); !ok || user != "x-access-token" || pass != "ghs_2" {
+		t.Errorf("SetAuth() basic auth = %q, %q", user, pass)
+	}
+}
+
+func TestGitHubApp_Errors(t *testing.T) {
+	key, keyPEM := rsaKeyPEM(t)
+	_, otherPEM := rsaKeyPEM(t)
+	var minted int
+	server := fakeGitHub(t, key, &minted)
+	defer server.Close()
+
+	if _, err := NewGitHubApp(7, 42, "not a key", server.URL); err == nil {
+		t.Error("NewGitHubApp() should reject an invalid private key")
+	}
+	if _, err := NewGitHubApp(7, 0, keyPEM, server.URL); err == nil {
+		t.Error("NewGitHubApp() should require an installation ID")
+	}
+
+	app, err := NewGitHubApp(7, 42, otherPEM, server.URL)
+	if err != nil {
+		t.Fatalf("NewGitHubApp() error = %v", err)
+	}
+	if _, err := app.Token(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Token() error = %v, want a 401 from an unknown key", err)
+	}
+}
@@ -1,18 +1,28 @@
 package git
 
 import (
+	"context"
+	"encoding/hex"
 	"fmt"
-	"log"
+	"io"
+	"io/fs"
+	"log/slog"
 	"os"
 	"strings"
+	gosync "sync"
 	"time"
 
 	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
-	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
-	"golang.org/x/crypto/ssh"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"grafana_git_sync/pkg/tracing"
 )
 
 // CommitInfo contains metadata about a Git commit
@@ -27,82 +37,449 @@ type CommitInfo struct {
 // Client handles Git operations
 type Client struct {
 	repoURL  string
-	branch   string
+	ref      RefSelector
 	repoDir  string
+	authMu   gosync.RWMutex
 	auth     transport.AuthMethod
 	repo     *gogit.Repository
+	head     plumbing.Hash // last fetched commit
+	resolved RefSelector   // revision the selector resolved to on the last fetch
+
+	verifyMode VerifyMode
+	keyring    *Keyring
+	verified   plumbing.Hash // last commit whose signatures were verified
+}
+
+// headRef is the reference the fetched revision is stored under in the local clone
+const headRef = plumbing.ReferenceName("refs/sync/head")
+
+var tracer = otel.Tracer("grafana_git_sync/pkg/git")
+
+// Credentials holds the secrets used to access the repository. SSH URLs use
+// the SSH key or agent; HTTPS URLs use, in order of preference, the GitHub App, the
+// bearer token or the username and password, and are accessed anonymously otherwise.
+type Credentials struct {
+	SSHKey           string
+	SSHKeyPassphrase string // for encrypted SSH keys
+	SSHAgent         bool   // authenticate with the agent at SSH_AUTH_SOCK instead of SSHKey
+	SSHUser          string // defaults to the user in the URL, then "git"
+
+	HTTPSUser     string
+	HTTPSPassword string
+	HTTPSToken    string // bearer token, e.g. a GitLab or Gitea access token
+
+	GitHubAppID          int64
+	GitHubInstallationID int64
+	GitHubAppKey         string // PEM-encoded App private key
+	GitHubAPIURL         string // defaults to DefaultGitHubAPIURL
 }
 
-// NewClient creates a new Git client with authentication
+// NewClient creates a new Git client with authentication that follows branch
 func NewClient(repoURL, branch, repoDir, sshKey, httpsUser, httpsPassword string) (*Client, error) {
-	auth, err := createAuth(repoURL, sshKey, httpsUser, httpsPassword)
+	return NewClientWithRef(repoURL, BranchRef(branch), repoDir, Credentials{
+		SSHKey:        sshKey,
+		HTTPSUser:     httpsUser,
+		HTTPSPassword: httpsPassword,
+	})
+}
+
+// NewClientWithRef creates a new Git client with authentication that follows
+// a branch, tag, commit or semver tag range
+func NewClientWithRef(repoURL string, ref RefSelector, repoDir string, creds Credentials) (*Client, error) {
+	auth, err := createAuth(repoURL, creds)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create git auth: %w", err)
 	}
 
 	return &Client{
 		repoURL: repoURL,
-		branch:  branch,
+		ref:     ref,
 		repoDir: repoDir,
 		auth:    auth,
 	}, nil
 }
 
-// Clone clones the repository to the local directory
-func (c *Client) Clone() error {
-	log.Println("📥 Cloning repo...")
+// SetVerification refuses fetched commits that are not signed by a key in
+// keyring. It must be called before Clone: with VerifyAll the full history is
+// fetched instead of a shallow clone, so every new commit can be checked.
+func (c *Client) SetVerification(mode VerifyMode, keyring *Keyring) {
+	c.verifyMode = mode
+	c.keyring = keyring
+}
+
+// depth returns the fetch depth, 0 for the full history
+func (c *Client) depth() int {
+	if c.verifyMode == VerifyAll {
+		return 0
+	}
+	return 1
+}
+
+// Clone clones the repository to the local directory. The clone is bare:
+// files are read from the commit tree (see TreeFS), not from a checkout.
+func (c *Client) Clone(ctx context.Context) error {
+	slog.Info("Cloning repository", "ref", c.ref.String())
+	start := time.Now()
 
 	// Remove old repo directory if exists
 	if err := os.RemoveAll(c.repoDir); err != nil {
 		return fmt.Errorf("failed to remove old repo directory: %w", err)
 	}
 
-	repo, err := gogit.PlainClone(c.repoDir, false, &gogit.CloneOptions{
-		URL:           c.repoURL,
-		ReferenceName: plumbing.NewBranchReferenceName(c.branch),
-		SingleBranch:  true,
-		Depth:         1,
-		Auth:          c.auth,
-		Progress:      os.Stdout,
-	})
+	repo, err := gogit.PlainInit(c.repoDir, true)
 	if err != nil {
-		return fmt.Errorf("failed to clone repo: %w", err)
+		return fmt.Errorf("failed to initialize repo: %w", err)
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{c.repoURL}}); err != nil {
+		return fmt.Errorf("failed to add remote: %w", err)
+	}
+
+	// Git progress output is unstructured, so it is only shown when debugging
+	var progress io.Writer
+	if slog.Default().Enabled(ctx, slog.LevelDebug) {
+		progress = os.Stderr
 	}
 
 	c.repo = repo
-	log.Println("✅ Repo cloned successfully")
+	if err := c.fetch(ctx, progress); err != nil {
+		return fmt.Errorf("failed to clone repo: %w", err)
+	}
+	slog.Info("Repository cloned", "ref", c.resolved.String(), "commit", c.head.String(), "duration", time.Since(start))
 	return nil
 }
 
-// FetchLatestCommit pulls the latest changes and returns the commit hash
-func (c *Client) FetchLatestCommit() (string, error) {
+// FetchLatestCommit fetches the latest changes and returns the commit hash
+func (c *Client) FetchLatestCommit(ctx context.Context) (string, error) {
 	if c.repo == nil {
 		return "", fmt.Errorf("repository not initialized, call Clone first")
 	}
 
-	w, err := c.repo.Worktree()
+	if err := c.fetch(ctx, nil); err != nil {
+		return "", fmt.Errorf("fetch failed: %w", err)
+	}
+	return c.head.String(), nil
+}
+
+// Ref returns the revision the last fetch resolved to, e.g. "tag:v2.1.0" for a semver selector
+func (c *Client) Ref() string {
+	return c.resolved.String()
+}
+
+// TreeFS returns a read-only file system over the tree of the last fetched commit
+func (c *Client) TreeFS() (fs.FS, error) {
+	if c.repo == nil {
+		return nil, fmt.Errorf("repository not initialized")
+	}
+
+	commit, err := c.repo.CommitObject(c.head)
 	if err != nil {
-		return "", fmt.Errorf("failed to get worktree: %w", err)
+		return nil, fmt.Errorf("failed to get commit object: %w", err)
 	}
+	fsys, err := NewTreeFS(commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit tree: %w", err)
+	}
+	return fsys, nil
+}
 
-	err = w.Pull(&gogit.PullOptions{
-		RemoteName:    "origin",
-		ReferenceName: plumbing.NewBranchReferenceName(c.branch),
-		SingleBranch:  true,
-		Force:         true,
-		Auth:          c.auth,
+// fetch resolves the selector against the remote, fetches the selected
+// revision into headRef and records the commit it points to
+func (c *Client) fetch(ctx context.Context, progress io.Writer) (err error) {
+	ctx, span := tracer.Start(ctx, "git.fetch", trace.WithAttributes(tracing.Ref.String(c.ref.String())))
+	defer func() {
+		if err != nil {
+			tracing.Fail(span, err)
+		} else {
+			span.SetAttributes(tracing.Commit.String(c.head.String()), attribute.String("git.ref.resolved", c.resolved.String()))
+		}
+		span.End()
+	}()
+
+	if c.ref.Kind == RefCommit {
+		return c.fetchCommit(ctx, progress)
+	}
+	return c.fetchRef(ctx, progress)
+}
+
+// fetchRef fetches the branch or tag the selector resolves to
+func (c *Client) fetchRef(ctx context.Context, progress io.Writer) error {
+	src, resolved, err := c.resolveRef(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.repo.FetchContext(ctx, &gogit.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", src, headRef))},
+		Depth:      c.depth(),
+		Tags:       gogit.NoTags,
+		Force:      true,
+		Auth:       c.authMethod(),
+		Progress:   progress,
 	})
+	if !fetched(err) {
+		return err
+	}
 
-	if err != nil && err != gogit.NoErrAlreadyUpToDate && !strings.Contains(err.Error(), "empty git-upload-pack") {
-		return "", fmt.Errorf("pull failed: %w", err)
+	head, err := c.resolveHead()
+	if err != nil {
+		return err
+	}
+	if err := c.accept(head); err != nil {
+		return err
+	}
+	c.resolved = resolved
+	return nil
+}
+
+// fetchCommit fetches a pinned commit. Commits never change, so nothing is
+// fetched once the commit is in the local clone.
+func (c *Client) fetchCommit(ctx context.Context, progress io.Writer) error {
+	hash := plumbing.NewHash(c.ref.Value)
+	if err := c.fetchHash(ctx, hash, progress); err != nil {
+		return err
+	}
+	if err := c.accept(hash); err != nil {
+		return err
+	}
+	c.resolved = c.ref
+	return nil
+}
+
+// fetchHash fetches the commit hash unless it is already in the local clone
+func (c *Client) fetchHash(ctx context.Context, hash plumbing.Hash, progress io.Writer) error {
+	if _, err := c.repo.CommitObject(hash); err == nil {
+		return nil
+	}
+	opts := &gogit.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("%s:%s", hash, headRef))},
+		Depth:      c.depth(),
+		Tags:       gogit.NoTags,
+		Auth:       c.authMethod(),
+		Progress:   progress,
+	}
+	err := c.repo.FetchContext(ctx, opts)
+	if !fetched(err) {
+		// Not every server serves commits by SHA; look for it in the full history instead
+		slog.Warn("Fetching commit directly failed, fetching all branches", "commit", hash.String(), "error", err)
+		opts.RefSpecs = []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"}
+		opts.Depth = 1 << 30 // a shallow clone is only deepened by an explicit depth
+		opts.Force = true
+		if err := c.repo.FetchContext(ctx, opts); !fetched(err) {
+			return err
+		}
+	}
+	if _, err := c.repo.CommitObject(hash); err != nil {
+		return fmt.Errorf("commit %s not found in the repository: %w", hash, err)
+	}
+	return nil
+}
+
+// Checkout makes an earlier commit the head, e.g. to roll back a bad change.
+// rev is a full commit SHA or "previous" for the parent of the current head.
+// With signature verification enabled the commit itself must be signed by a
+// trusted key. The next FetchLatestCommit returns to the selected ref.
+func (c *Client) Checkout(ctx context.Context, rev string) (_ string, err error) {
+	if c.repo == nil {
+		return "", fmt.Errorf("repository not initialized, call Clone first")
+	}
+	if err := ValidateRevision(rev); err != nil {
+		return "", err
+	}
+
+	ctx, span := tracer.Start(ctx, "git.checkout", trace.WithAttributes(attribute.String("git.checkout.rev", rev)))
+	defer func() {
+		if err != nil {
+			tracing.Fail(span, err)
+		} else {
+			span.SetAttributes(tracing.Commit.String(c.head.String()))
+		}
+		span.End()
+	}()
+
+	hash := plumbing.NewHash(rev)
+	if rev == PreviousCommit {
+		head, err := c.repo.CommitObject(c.head)
+		if err != nil {
+			return "", fmt.Errorf("failed to get commit object: %w", err)
+		}
+		// Shallow clones lack the parent object but the commit records its hash
+		if len(head.ParentHashes) == 0 {
+			return "", fmt.Errorf("commit %s has no parent to roll back to", c.head)
+		}
+		hash = head.ParentHashes[0]
 	}
 
-	ref, err := c.repo.Head()
+	if err := c.fetchHash(ctx, hash, nil); err != nil {
+		return "", err
+	}
+	if c.verifyMode == VerifyHead || c.verifyMode == VerifyAll {
+		commit, err := c.repo.CommitObject(hash)
+		if err != nil {
+			return "", err
+		}
+		if _, err := c.keyring.Verify(commit); err != nil {
+			slog.Error("Refusing to check out commit", "commit", hash.String(), "error", err)
+			return "", err
+		}
+	}
+	c.head = hash
+	c.resolved = RefSelector{Kind: RefCommit, Value: hash.String()}
+	return hash.String(), nil
+}
+
+// PreviousCommit selects the parent of the current head in Checkout
+const PreviousCommit = "previous"
+
+// ValidateRevision checks that rev can be passed to Checkout
+func ValidateRevision(rev string) error {
+	if rev == PreviousCommit {
+		return nil
+	}
+	if _, err := hex.DecodeString(rev); err != nil || len(rev) != 40 {
+		return fmt.Errorf("invalid commit %q (want a full 40-character SHA or %s)", rev, PreviousCommit)
+	}
+	return nil
+}
+
+// resolveRef returns the remote reference to fetch for the selector and the
+// revision it resolves to
+func (c *Client) resolveRef(ctx context.Context) (plumbing.ReferenceName, RefSelector, error) {
+	switch c.ref.Kind {
+	case RefTag:
+		return plumbing.NewTagReferenceName(c.ref.Value), c.ref, nil
+	case RefSemver:
+		remote, err := c.repo.Remote("origin")
+		if err != nil {
+			return "", RefSelector{}, err
+		}
+		refs, err := remote.ListContext(ctx, &gogit.ListOptions{Auth: c.authMethod()})
+		if err != nil {
+			return "", RefSelector{}, fmt.Errorf("failed to list remote tags: %w", err)
+		}
+		var tags []string
+		for _, ref := range refs {
+			if ref.Name().IsTag() {
+				tags = append(tags, ref.Name().Short())
+			}
+		}
+		tag, ok := c.ref.latestTag(tags)
+		if !ok {
+			return "", RefSelector{}, fmt.Errorf("no tag matches %s", c.ref)
+		}
+		return plumbing.NewTagReferenceName(tag), RefSelector{Kind: RefTag, Value: tag}, nil
+	default:
+		return plumbing.NewBranchReferenceName(c.ref.Value), c.ref, nil
+	}
+}
+
+// resolveHead returns the commit headRef points to, peeling annotated tags
+func (c *Client) resolveHead() (plumbing.Hash, error) {
+	ref, err := c.repo.Reference(headRef, true)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to resolve %s: %w", c.ref, err)
+	}
+	hash := ref.Hash()
+	if tag, err := c.repo.TagObject(hash); err == nil {
+		commit, err := tag.Commit()
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("tag %s does not point to a commit: %w", tag.Name, err)
+		}
+		hash = commit.Hash
+	}
+	return hash, nil
+}
+
+// accept makes a fetched commit the head once its signatures are verified.
+// A refused commit leaves the previous head in place.
+func (c *Client) accept(hash plumbing.Hash) error {
+	if (c.verifyMode == VerifyHead || c.verifyMode == VerifyAll) && hash != c.verified {
+		if err := c.verify(hash); err != nil {
+			return err
+		}
+		c.verified = hash
+	}
+	c.head = hash
+	return nil
+}
+
+// verify checks the signature of the commit hash and, with VerifyAll, of every
+// commit since the last verified one. The first fetch only checks the commit itself.
+func (c *Client) verify(hash plumbing.Hash) error {
+	commits, err := c.unverifiedCommits(hash)
+	if err != nil {
+		return fmt.Errorf("failed to list commits to verify: %w", err)
+	}
+	for _, commit := range commits {
+		signer, err := c.keyring.Verify(commit)
+		if err != nil {
+			slog.Error("Refusing to sync commit", "commit", hash.String(), "error", err)
+			return err
+		}
+		if commit.Hash == hash {
+			slog.Info("Commit signature verified", "commit", hash.String(), "signer", signer)
+		}
+	}
+	if len(commits) > 1 {
+		slog.Info("Verified commit signatures", "count", len(commits))
+	}
+	return nil
+}
+
+// unverifiedCommits returns the commits whose signatures must be checked before hash is synced
+func (c *Client) unverifiedCommits(hash plumbing.Hash) ([]*object.Commit, error) {
+	head, err := c.repo.CommitObject(hash)
 	if err != nil {
-		return "", fmt.Errorf("failed to get HEAD: %w", err)
+		return nil, err
+	}
+	if c.verifyMode != VerifyAll || c.verified.IsZero() {
+		return []*object.Commit{head}, nil
 	}
 
-	return ref.Hash().String(), nil
+	previous, err := c.repo.CommitObject(c.verified)
+	if err != nil {
+		return nil, err
+	}
+	// Everything reachable from the last verified commit was checked, or predates the first sync
+	checked := make(map[plumbing.Hash]bool)
+	err = object.NewCommitPreorderIter(previous, nil, nil).ForEach(func(commit *object.Commit) error {
+		checked[commit.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []*object.Commit
+	err = object.NewCommitPreorderIter(head, checked, nil).ForEach(func(commit *object.Commit) error {
+		commits = append(commits, commit)
+		return nil
+	})
+	return commits, err
+}
+
+// fetched reports whether a fetch succeeded, including when there was nothing new
+func fetched(err error) bool {
+	return err == nil || err == gogit.NoErrAlreadyUpToDate || strings.Contains(err.Error(), "empty git-upload-pack")
+}
+
+// UpdateCredentials replaces the credentials used for later fetches, e.g. after a secret rotation
+func (c *Client) UpdateCredentials(creds Credentials) error {
+	auth, err := createAuth(c.repoURL, creds)
+	if err != nil {
+		return fmt.Errorf("failed to create git auth: %w", err)
+	}
+
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.auth = auth
+	return nil
+}
+
+func (c *Client) authMethod() transport.AuthMethod {
+	c.authMu.RLock()
+	defer c.authMu.RUnlock()
+	return c.auth
 }
 
 // GetCommitInfo returns detailed information about the current HEAD commit
@@ -111,12 +488,7 @@ func (c *Client) GetCommitInfo() (*CommitInfo, error) {
 		return nil, fmt.Errorf("repository not initialized")
 	}
 
-	ref, err := c.repo.Head()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get HEAD: %w", err)
-	}
-
-	commit, err := c.repo.CommitObject(ref.Hash())
+	commit, err := c.repo.CommitObject(c.head)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get commit object: %w", err)
 	}
@@ -130,41 +502,45 @@ func (c *Client) GetCommitInfo() (*CommitInfo, error) {
 	}, nil
 }
 
-func createAuth(repoURL, sshKey, httpsUser, httpsPassword string) (transport.AuthMethod, error) {
+func createAuth(repoURL string, creds Credentials) (transport.AuthMethod, error) {
 	// SSH authentication
-	if sshKey != "" && (strings.HasPrefix(repoURL, "ssh://") || strings.HasPrefix(repoURL, "git@")) {
-		keyStr := strings.ReplaceAll(sshKey, `\n`, "\n")
-
-		if strings.TrimSpace(keyStr) == "" {
-			return nil, fmt.Errorf("SSH key is empty")
-		}
+	if IsSSHURL(repoURL) {
+		return createSSHAuth(repoURL, creds)
+	}
+	if creds.SSHKey != "" || creds.SSHAgent {
+		return nil, fmt.Errorf("SSH authentication is configured but %s is not an SSH URL; use ssh://[user@]host[:port]/path or [user@]host:path, or HTTPS credentials", repoURL)
+	}
 
-		signer, err := ssh.ParsePrivateKey([]byte(keyStr))
+	// GitHub App installation tokens
+	if creds.GitHubAppID != 0 || creds.GitHubAppKey != "" {
+		app, err := NewGitHubApp(creds.GitHubAppID, creds.GitHubInstallationID, creds.GitHubAppKey, creds.GitHubAPIURL)
 		if err != nil {
-			return nil, fmt.Errorf("unable to parse private key: %w", err)
+			return nil, err
 		}
+		slog.Info("Using GitHub App auth", "app_id", creds.GitHubAppID, "installation_id", creds.GitHubInstallationID)
+		return &gitHubAppAuth{app: app}, nil
+	}
 
-		log.Printf("🔑 SSH key successfully parsed, length: %d bytes", len(keyStr))
-
-		auth := &gitssh.PublicKeys{
-			User:   "git",
-			Signer: signer,
-			HostKeyCallbackHelper: gitssh.HostKeyCallbackHelper{
-				HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-			},
-		}
-		log.Println("✅ Using SSH auth with key from environment")
-		return auth, nil
+	// Bearer token authentication
+	if creds.HTTPSToken != "" {
+		slog.Info("Using HTTPS bearer token auth")
+		return &githttp.TokenAuth{Token: creds.HTTPSToken}, nil
 	}
 
 	// HTTPS authentication
-	if httpsUser != "" && httpsPassword != "" {
-		log.Println("🔑 Using HTTPS auth")
+	if creds.HTTPSUser != "" && creds.HTTPSPassword != "" {
+		slog.Info("Using HTTPS auth", "user", creds.HTTPSUser)
 		return &githttp.BasicAuth{
-			Username: httpsUser,
-			Password: httpsPassword,
+			Username: creds.HTTPSUser,
+			Password: creds.HTTPSPassword,
 		}, nil
 	}
 
+	// Public repositories can be cloned over HTTP(S) without credentials, local ones always can
+	if strings.HasPrefix(repoURL, "https://") || strings.HasPrefix(repoURL, "http://") || strings.HasPrefix(repoURL, "file://") {
+		slog.Info("No Git credentials provided, accessing repository anonymously")
+		return nil, nil
+	}
+
 	return nil, fmt.Errorf("no authentication provided")
 }
@@ -1,7 +1,10 @@
 package git
 
 import (
+	"context"
 	"testing"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 )
 
 func TestNewClient(t *testing.T) {
@@ -63,7 +66,7 @@ func TestClient_Clone_InvalidURL(t *testing.T) {
 		t.Fatalf("NewClient() error = %v", err)
 	}
 
-	err = client.Clone()
+	err = client.Clone(context.Background())
 	if err == nil {
 		t.Error("Clone() should fail with invalid URL")
 	}
@@ -77,7 +80,7 @@ func TestClient_FetchLatestCommit_NoRepo(t *testing.T) {
 	}
 
 	// Try to fetch without cloning first
-	_, err = client.FetchLatestCommit()
+	_, err = client.FetchLatestCommit(context.Background())
 	if err == nil {
 		t.Error("FetchLatestCommit() should fail when repository is not cloned")
 	}
@@ -121,3 +124,53 @@ func TestExtractCredentials(t *testing.T) {
 		})
 	}
 }
+
+func TestNewClient_AnonymousHTTPS(t *testing.T) {
+	client, err := NewClient("https://github.com/test/repo.git", "main", t.TempDir(), "", "", "")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if client.auth != nil {
+		t.Error("Expected no auth for anonymous HTTPS access")
+	}
+}
+
+func TestClient_UpdateCredentials(t *testing.T) {
+	client, err := NewClient("https://github.com/test/repo.git", "main", t.TempDir(), "", "", "")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	if err := client.UpdateCredentials(Credentials{HTTPSUser: "user", HTTPSPassword: "rotated"}); err != nil {
+		t.Fatalf("UpdateCredentials() error = %v", err)
+	}
+	auth, ok := client.authMethod().(*githttp.BasicAuth)
+	if !ok || auth.Password != "rotated" {
+		t.Errorf("authMethod() = %#v, want basic auth with rotated password", client.authMethod())
+	}
+}
+
+func TestNewClientWithRef_TokenAuth(t *testing.T) {
+	_, keyPEM := rsaKeyPEM(t)
+	tests := []struct {
+		name  string
+		creds Credentials
+		want  string
+	}{
+		{name: "bearer token", creds: Credentials{HTTPSToken: "glpat-xxx"}, want: "http-token-auth"},
+		{name: "GitHub App", creds: Credentials{GitHubAppID: 7, GitHubInstallationID: 42, GitHubAppKey: keyPEM}, want: "http-github-app"},
+		{name: "GitHub App preferred", creds: Credentials{HTTPSToken: "ghp_xxx", GitHubAppID: 7, GitHubInstallationID: 42, GitHubAppKey: keyPEM}, want: "http-github-app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClientWithRef("https://github.com/test/repo.git", BranchRef("main"), t.TempDir(), tt.creds)
+			if err != nil {
+				t.Fatalf("NewClientWithRef() error = %v", err)
+			}
+			if got := client.authMethod().Name(); got != tt.want {
+				t.Errorf("auth method = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/mod/semver"
+)
+
+// RefKind is the kind of revision a RefSelector follows
+type RefKind string
+
+const (
+	RefBranch RefKind = "branch" // the head of a branch
+	RefTag    RefKind = "tag"    // a single tag
+	RefCommit RefKind = "commit" // a fixed commit SHA
+	RefSemver RefKind = "semver" // the newest tag matching a semantic version range
+)
+
+// RefSelector selects the revision to sync
+type RefSelector struct {
+	Kind  RefKind
+	Value string // branch or tag name, commit SHA or version range
+
+	versions versionRange // parsed Value of a semver selector
+}
+
+// BranchRef selects the head of branch
+func BranchRef(branch string) RefSelector {
+	return RefSelector{Kind: RefBranch, Value: branch}
+}
+
+// ParseRef parses a selector of the form "kind:value", e.g. "branch:main",
+// "tag:v1.4.0", "commit:<40-character SHA>" or "semver:v2.*". A value without
+// a kind is a branch name.
+func ParseRef(spec string) (RefSelector, error) {
+	kind, value, found := strings.Cut(strings.TrimSpace(spec), ":")
+	if !found {
+		kind, value = string(RefBranch), kind
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return RefSelector{}, fmt.Errorf("ref %q has no value", spec)
+	}
+
+	ref := RefSelector{Kind: RefKind(kind), Value: value}
+	switch ref.Kind {
+	case RefBranch, RefTag:
+		name := plumbing.NewBranchReferenceName(value)
+		if ref.Kind == RefTag {
+			name = plumbing.NewTagReferenceName(value)
+		}
+		if err := name.Validate(); err != nil {
+			return RefSelector{}, fmt.Errorf("invalid %s name %q: %w", kind, value, err)
+		}
+	case RefCommit:
+		if len(value) != 40 || !plumbing.IsHash(value) {
+			return RefSelector{}, fmt.Errorf("commit %q must be a full 40-character SHA", value)
+		}
+		ref.Value = strings.ToLower(value)
+	case RefSemver:
+		versions, err := parseVersionRange(value)
+		if err != nil {
+			return RefSelector{}, err
+		}
+		ref.versions = versions
+	default:
+		return RefSelector{}, fmt.Errorf("unknown ref kind %q (want branch, tag, commit or semver)", kind)
+	}
+	return ref, nil
+}
+
+// String returns the selector in the form accepted by ParseRef
+func (r RefSelector) String() string {
+	return string(r.Kind) + ":" + r.Value
+}
+
+// latestTag returns the highest tag in tags matching the semver range. Tags
+// may omit the leading "v"; pre-releases only match ranges that name one.
+func (r RefSelector) latestTag(tags []string) (string, bool) {
+	var best, bestVersion string
+	sort.Strings(tags) // prefer the same tag when several spell one version, e.g. v1.2 and v1.2.0
+	for _, tag := range tags {
+		version := tag
+		if !strings.HasPrefix(version, "v") {
+			version = "v" + version
+		}
+		if !semver.IsValid(version) || !r.versions.matches(version) {
+			continue
+		}
+		if best == "" || semver.Compare(version, bestVersion) > 0 {
+			best, bestVersion = tag, version
+		}
+	}
+	return best, best != ""
+}
+
+// versionRange is a set of comparisons a version must all satisfy
+type versionRange struct {
+	comparisons []comparison
+	prerelease  bool // whether pre-release versions may match
+}
+
+type comparison struct {
+	op      string // one of <, <=, >, >=, =
+	version string // canonical semver with a "v" prefix
+}
+
+// parseVersionRange parses space- or comma-separated terms, each either a
+// comparison (">=v2.1.0", "<3"), a wildcard ("v2.*", "2.1.x", "v2") or a
+// tilde/caret range ("~v2.1", "^2.1.0"). Leading "v"s are optional.
+func parseVersionRange(spec string) (versionRange, error) {
+	var r versionRange
+	terms := strings.FieldsFunc(spec, func(c rune) bool { return c == ' ' || c == ',' })
+	for _, term := range terms {
+		op := ""
+		for _, prefix := range []string{">=", "<=", ">", "<", "=", "~", "^"} {
+			if strings.HasPrefix(term, prefix) {
+				op, term = prefix, strings.TrimSpace(term[len(prefix):])
+				break
+			}
+		}
+		if term == "" {
+			return versionRange{}, fmt.Errorf("invalid version range %q", spec)
+		}
+		if term == "*" || term == "x" || term == "X" {
+			continue
+		}
+		if !strings.HasPrefix(term, "v") {
+			term = "v" + term
+		}
+
+		// Count the version parts before any wildcard, e.g. 2 for v2.1.*
+		parts := strings.Split(strings.SplitN(strings.SplitN(term, "-", 2)[0], "+", 2)[0], ".")
+		fixed := len(parts)
+		for i, part := range parts {
+			if part == "*" || part == "x" || part == "X" {
+				if i == 0 {
+					return versionRange{}, fmt.Errorf("invalid version %q in range %q", term, spec)
+				}
+				fixed = i
+				term = strings.Join(parts[:i], ".")
+				break
+			}
+		}
+		if !semver.IsValid(term) {
+			return versionRange{}, fmt.Errorf("invalid version %q in range %q", term, spec)
+		}
+		version := semver.Canonical(term)
+		if semver.Prerelease(version) != "" {
+			r.prerelease = true
+		}
+
+		switch {
+		case op == "~" || ((op == "" || op == "=") && fixed < 3):
+			// ~v2.1.3 and v2.1.* stay below v2.2.0, ~v2 and v2.* below v3.0.0
+			r.comparisons = append(r.comparisons, comparison{">=", version}, comparison{"<", bump(version, min(fixed, 2))})
+		case op == "^":
+			// ^v2.1.3 stays below v3.0.0, ^v0.2.3 below v0.3.0
+			major, minor := versionParts(version)
+			upper := 1
+			if major == 0 && (minor != 0 || fixed == 2) {
+				upper = 2
+			}
+			r.comparisons = append(r.comparisons, comparison{">=", version}, comparison{"<", bump(version, upper)})
+		case op == "":
+			r.comparisons = append(r.comparisons, comparison{"=", version})
+		default:
+			r.comparisons = append(r.comparisons, comparison{op, version})
+		}
+	}
+	return r, nil
+}
+
+// matches reports whether the canonical version satisfies the range
+func (r versionRange) matches(version string) bool {
+	if semver.Prerelease(version) != "" && !r.prerelease {
+		return false
+	}
+	for _, c := range r.comparisons {
+		cmp := semver.Compare(version, c.version)
+		var ok bool
+		switch c.op {
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		default:
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// versionParts returns the major and minor numbers of a canonical version
+func versionParts(version string) (major, minor int) {
+	parts := strings.SplitN(strings.TrimPrefix(semver.MajorMinor(version), "v"), ".", 2)
+	major, _ = strconv.Atoi(parts[0])
+	minor, _ = strconv.Atoi(parts[1])
+	return major, minor
+}
+
+// bump returns the smallest pre-release of the version after incrementing
+// the given part (1 = major, 2 = minor), so that "<" excludes its pre-releases too
+func bump(version string, part int) string {
+	major, minor := versionParts(version)
+	if part == 1 {
+		return fmt.Sprintf("v%d.0.0-0", major+1)
+	}
+	return fmt.Sprintf("v%d.%d.0-0", major, minor+1)
+}
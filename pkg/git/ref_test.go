@@ -0,0 +1,144 @@
+package git
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{spec: "main", want: "branch:main"},
+		{spec: "branch:release/2.x", want: "branch:release/2.x"},
+		{spec: "tag:v1.4.0", want: "tag:v1.4.0"},
+		{spec: "commit:0123456789ABCDEF0123456789abcdef01234567", want: "commit:0123456789abcdef0123456789abcdef01234567"},
+		{spec: "semver:v2.*", want: "semver:v2.*"},
+		{spec: "commit:0123abc", wantErr: true},
+		{spec: "tag:", wantErr: true},
+		{spec: "branch:a..b", wantErr: true},
+		{spec: "semver:latest", wantErr: true},
+		{spec: "head:main", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			ref, err := ParseRef(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && ref.String() != tt.want {
+				t.Errorf("ParseRef() = %s, want %s", ref, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefSelector_LatestTag(t *testing.T) {
+	tags := []string{"v1.9.0", "v2.0.0", "v2.1.0", "v2.1.5", "2.2.0", "v2.3.0-rc.1", "v3.0.0", "latest", "v10.0.0"}
+	tests := []struct {
+		versions string
+		want     string
+	}{
+		{versions: "v2.*", want: "2.2.0"},
+		{versions: "v2", want: "2.2.0"},
+		{versions: "2.1.x", want: "v2.1.5"},
+		{versions: "~v2.1.0", want: "v2.1.5"},
+		{versions: "^v2.0.0", want: "2.2.0"},
+		{versions: ">=v2.0.0 <v2.2.0", want: "v2.1.5"},
+		{versions: ">=v2.3.0-rc.1, <v3", want: "v2.3.0-rc.1"},
+		{versions: "v2.1.0", want: "v2.1.0"},
+		{versions: "*", want: "v10.0.0"},
+		{versions: "v4.*", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.versions, func(t *testing.T) {
+			ref, err := ParseRef("semver:" + tt.versions)
+			if err != nil {
+				t.Fatalf("ParseRef() error = %v", err)
+			}
+			if got, _ := ref.latestTag(tags); got != tt.want {
+				t.Errorf("latestTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_FetchRefs(t *testing.T) {
+	srcDir, src := initRepo(t)
+	first := commitFiles(t, srcDir, src, map[string]string{"cpu.json": `{"title": "CPU"}`})
+	if _, err := src.CreateTag("v1.0.0", first, nil); err != nil {
+		t.Fatal(err)
+	}
+	second := commitFiles(t, srcDir, src, map[string]string{"mem.json": `{"title": "Memory"}`})
+	if _, err := src.CreateTag("v1.1.0", second, &gogit.CreateTagOptions{
+		Message: "release 1.1.0",
+		Tagger:  &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	third := commitFiles(t, srcDir, src, map[string]string{"disk.json": `{"title": "Disk"}`})
+
+	tests := []struct {
+		spec    string
+		want    string
+		wantRef string
+	}{
+		{spec: "branch:main", want: third.String(), wantRef: "branch:main"},
+		{spec: "tag:v1.0.0", want: first.String(), wantRef: "tag:v1.0.0"},
+		{spec: "tag:v1.1.0", want: second.String(), wantRef: "tag:v1.1.0"}, // annotated
+		{spec: "semver:v1.*", want: second.String(), wantRef: "tag:v1.1.0"},
+		{spec: "commit:" + first.String(), want: first.String(), wantRef: "commit:" + first.String()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			ref, err := ParseRef(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseRef() error = %v", err)
+			}
+			client, err := NewClientWithRef("file://"+srcDir, ref, filepath.Join(t.TempDir(), "clone"), Credentials{})
+			if err != nil {
+				t.Fatalf("NewClientWithRef() error = %v", err)
+			}
+			if err := client.Clone(context.Background()); err != nil {
+				t.Fatalf("Clone() error = %v", err)
+			}
+
+			got, err := client.FetchLatestCommit(context.Background())
+			if err != nil {
+				t.Fatalf("FetchLatestCommit() error = %v", err)
+			}
+			if got != tt.want || client.Ref() != tt.wantRef {
+				t.Errorf("FetchLatestCommit() = %s at %s, want %s at %s", got, client.Ref(), tt.want, tt.wantRef)
+			}
+		})
+	}
+
+	// A new matching release is picked up by the next fetch
+	t.Run("semver follows new tags", func(t *testing.T) {
+		ref, _ := ParseRef("semver:v1.*")
+		client, err := NewClientWithRef("file://"+srcDir, ref, filepath.Join(t.TempDir(), "clone"), Credentials{})
+		if err != nil {
+			t.Fatalf("NewClientWithRef() error = %v", err)
+		}
+		if err := client.Clone(context.Background()); err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+		if _, err := src.CreateTag("v1.2.0", third, nil); err != nil {
+			t.Fatal(err)
+		}
+		got, err := client.FetchLatestCommit(context.Background())
+		if err != nil || got != third.String() || client.Ref() != "tag:v1.2.0" {
+			t.Errorf("FetchLatestCommit() = %s at %s, %v", got, client.Ref(), err)
+		}
+	})
+}
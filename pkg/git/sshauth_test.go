@@ -0,0 +1,147 @@
+package git
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestIsSSHURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "ssh://git@github.com/org/repo.git", want: true},
+		{url: "ssh://deploy@git.example.com:2222/org/repo.git", want: true},
+		{url: "git@github.com:org/repo.git", want: true},
+		{url: "deploy@gitlab.example.com:org/repo.git", want: true},
+		{url: "git.example.com:org/repo.git", want: true},
+		{url: "https://github.com/org/repo.git", want: false},
+		{url: "file:///srv/git/repo.git", want: false},
+		{url: "/srv/git/repo.git", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := IsSSHURL(tt.url); got != tt.want {
+				t.Errorf("IsSSHURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateAuth_SSH(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := string(pem.EncodeToMemory(block))
+	block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("s3cret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := string(pem.EncodeToMemory(block))
+
+	tests := []struct {
+		name     string
+		url      string
+		creds    Credentials
+		wantUser string
+		wantErr  string
+	}{
+		{name: "default user", url: "git@github.com:org/repo.git", creds: Credentials{SSHKey: plain}, wantUser: "git"},
+		{name: "user from scp-like URL", url: "deploy@git.example.com:org/repo.git", creds: Credentials{SSHKey: plain}, wantUser: "deploy"},
+		{name: "user from URL with port", url: "ssh://deploy@git.example.com:2222/org/repo.git", creds: Credentials{SSHKey: plain}, wantUser: "deploy"},
+		{name: "configured user", url: "ssh://git.example.com/org/repo.git", creds: Credentials{SSHKey: plain, SSHUser: "bot"}, wantUser: "bot"},
+		{name: "escaped newlines", url: "git@github.com:org/repo.git", creds: Credentials{SSHKey: strings.ReplaceAll(plain, "\n", `\n`)}, wantUser: "git"},
+		{name: "encrypted key", url: "git@github.com:org/repo.git", creds: Credentials{SSHKey: encrypted, SSHKeyPassphrase: "s3cret"}, wantUser: "git"},
+		{name: "missing passphrase", url: "git@github.com:org/repo.git", creds: Credentials{SSHKey: encrypted}, wantErr: "passphrase is required"},
+		{name: "wrong passphrase", url: "git@github.com:org/repo.git", creds: Credentials{SSHKey: encrypted, SSHKeyPassphrase: "guess"}, wantErr: "unable to decrypt"},
+		{name: "no key", url: "git@github.com:org/repo.git", wantErr: "no SSH key or agent"},
+		{name: "key for HTTPS URL", url: "https://github.com/org/repo.git", creds: Credentials{SSHKey: plain}, wantErr: "is not an SSH URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := createAuth(tt.url, tt.creds)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("createAuth() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("createAuth() error = %v", err)
+			}
+			keys, ok := auth.(*gitssh.PublicKeys)
+			if !ok || keys.User != tt.wantUser {
+				t.Errorf("createAuth() = %v, want public key auth as %s", auth, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestCreateAuth_SSHAgent(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unix socket paths are limited in length, so avoid the long t.TempDir path
+	dir, err := os.MkdirTemp("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "sock")
+	listener, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", sock)
+
+	auth, err := createAuth("deploy@git.example.com:org/repo.git", Credentials{SSHAgent: true})
+	if err != nil {
+		t.Fatalf("createAuth() error = %v", err)
+	}
+	cb, ok := auth.(*gitssh.PublicKeysCallback)
+	if !ok || cb.User != "deploy" {
+		t.Fatalf("createAuth() = %v, want agent auth as deploy", auth)
+	}
+	signers, err := cb.Callback()
+	if err != nil || len(signers) != 1 {
+		t.Errorf("agent signers = %d, %v", len(signers), err)
+	}
+
+	t.Setenv("SSH_AUTH_SOCK", "")
+	if _, err := createAuth("git@github.com:org/repo.git", Credentials{SSHAgent: true}); err == nil {
+		t.Error("createAuth() should fail without an agent")
+	}
+}
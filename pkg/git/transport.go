@@ -0,0 +1,32 @@
+package git
+
+import (
+	"net/http"
+	gosync "sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+var (
+	transportMu   gosync.RWMutex
+	httpTransport http.RoundTripper = http.DefaultTransport
+)
+
+// SetHTTPTransport routes Git over HTTP(S) and GitHub API requests through rt,
+// e.g. to apply a proxy or a custom CA bundle. It applies to every client in the process.
+func SetHTTPTransport(rt *http.Transport) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+
+	httpTransport = rt
+	gitClient := githttp.NewClient(&http.Client{Transport: rt})
+	client.InstallProtocol("http", gitClient)
+	client.InstallProtocol("https", gitClient)
+}
+
+func currentTransport() http.RoundTripper {
+	transportMu.RLock()
+	defer transportMu.RUnlock()
+	return httpTransport
+}
@@ -0,0 +1,188 @@
+package git
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// treeFS exposes a commit tree as a read-only fs.FS, so files are read straight
+// from the object store without a checkout. Symlinks are not followed; their
+// targets are available through ReadLink.
+type treeFS struct {
+	tree    *object.Tree
+	modTime time.Time // commit time, reported for every file
+}
+
+// NewTreeFS returns a read-only file system over the tree of commit
+func NewTreeFS(commit *object.Commit) (fs.FS, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	return &treeFS{tree: tree, modTime: commit.Committer.When}, nil
+}
+
+// Open opens the named file or directory
+func (t *treeFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &treeDir{fsys: t, name: ".", tree: t.tree}, nil
+	}
+
+	entry, err := t.tree.FindEntry(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.Mode == filemode.Dir {
+		sub, err := t.tree.Tree(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &treeDir{fsys: t, name: name, tree: sub}, nil
+	}
+	if entry.Mode == filemode.Submodule {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("submodules are not supported")}
+	}
+
+	file, err := t.tree.TreeEntryFile(entry)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	r, err := file.Reader()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &treeFile{ReadCloser: r, info: t.info(path.Base(name), entry.Mode, file.Size)}, nil
+}
+
+// ReadLink returns the target of the named symlink
+func (t *treeFS) ReadLink(name string) (string, error) {
+	entry, err := t.tree.FindEntry(name)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.Mode != filemode.Symlink {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	file, err := t.tree.TreeEntryFile(entry)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return file.Contents()
+}
+
+func (t *treeFS) info(name string, mode filemode.FileMode, size int64) *treeInfo {
+	return &treeInfo{name: name, mode: toFSMode(mode), size: size, modTime: t.modTime}
+}
+
+func toFSMode(mode filemode.FileMode) fs.FileMode {
+	switch mode {
+	case filemode.Dir:
+		return fs.ModeDir | 0o555
+	case filemode.Symlink:
+		return fs.ModeSymlink | 0o777
+	case filemode.Executable:
+		return 0o555
+	case filemode.Submodule:
+		return fs.ModeIrregular
+	default:
+		return 0o444
+	}
+}
+
+// treeFile is an open blob
+type treeFile struct {
+	io.ReadCloser
+	info *treeInfo
+}
+
+func (f *treeFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// treeDir is an open tree
+type treeDir struct {
+	fsys    *treeFS
+	name    string
+	tree    *object.Tree
+	entries []fs.DirEntry // remaining entries, loaded on the first ReadDir
+	loaded  bool
+}
+
+func (d *treeDir) Stat() (fs.FileInfo, error) {
+	return d.fsys.info(path.Base(d.name), filemode.Dir, 0), nil
+}
+
+func (d *treeDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *treeDir) Close() error { return nil }
+
+// ReadDir lists the entries of the tree in name order
+func (d *treeDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.loaded {
+		d.loaded = true
+		for i := range d.tree.Entries {
+			d.entries = append(d.entries, &treeDirEntry{dir: d, entry: &d.tree.Entries[i]})
+		}
+		sort.Slice(d.entries, func(i, j int) bool { return d.entries[i].Name() < d.entries[j].Name() })
+	}
+
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+// treeDirEntry is a tree entry; its size is only looked up when Info is called
+type treeDirEntry struct {
+	dir   *treeDir
+	entry *object.TreeEntry
+}
+
+func (e *treeDirEntry) Name() string      { return e.entry.Name }
+func (e *treeDirEntry) IsDir() bool       { return e.entry.Mode == filemode.Dir }
+func (e *treeDirEntry) Type() fs.FileMode { return toFSMode(e.entry.Mode).Type() }
+
+func (e *treeDirEntry) Info() (fs.FileInfo, error) {
+	var size int64
+	if e.entry.Mode.IsFile() {
+		file, err := e.dir.tree.TreeEntryFile(e.entry)
+		if err != nil {
+			return nil, err
+		}
+		size = file.Size
+	}
+	return e.dir.fsys.info(e.entry.Name, e.entry.Mode, size), nil
+}
+
+// treeInfo implements fs.FileInfo for tree entries
+type treeInfo struct {
+	name    string
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+}
+
+func (i *treeInfo) Name() string       { return i.name }
+func (i *treeInfo) Size() int64        { return i.size }
+func (i *treeInfo) Mode() fs.FileMode  { return i.mode }
+func (i *treeInfo) ModTime() time.Time { return i.modTime }
+func (i *treeInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *treeInfo) Sys() any           { return nil }
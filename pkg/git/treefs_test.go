@@ -0,0 +1,163 @@
+package git
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// initRepo creates a local repository on branch main to clone from
+func initRepo(t *testing.T) (string, *gogit.Repository) {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := gogit.PlainInitWithOptions(dir, &gogit.PlainInitOptions{
+		InitOptions: gogit.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	if err != nil {
+		t.Fatalf("PlainInit() error = %v", err)
+	}
+	return dir, repo
+}
+
+// commitFiles writes files into the repository and commits them
+func commitFiles(t *testing.T, dir string, repo *gogit.Repository, files map[string]string) plumbing.Hash {
+	t.Helper()
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash, err := w.Commit("update dashboards", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	return hash
+}
+
+func TestTreeFS(t *testing.T) {
+	dir, repo := initRepo(t)
+	hash := commitFiles(t, dir, repo, map[string]string{
+		"README.md":              "# dashboards",
+		"dashboards/cpu.json":    `{"title": "CPU"}`,
+		"dashboards/team/a.json": `{"title": "A"}`,
+	})
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsys, err := NewTreeFS(commit)
+	if err != nil {
+		t.Fatalf("NewTreeFS() error = %v", err)
+	}
+	if err := fstest.TestFS(fsys, "README.md", "dashboards/cpu.json", "dashboards/team/a.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.ReadFile(fsys, "dashboards/cpu.json")
+	if err != nil || string(data) != `{"title": "CPU"}` {
+		t.Errorf("ReadFile() = %q, %v", data, err)
+	}
+}
+
+func TestClient_CloneAndFetch(t *testing.T) {
+	srcDir, src := initRepo(t)
+	commitFiles(t, srcDir, src, map[string]string{"cpu.json": `{"title": "CPU"}`})
+
+	client, err := NewClient("file://"+srcDir, "main", filepath.Join(t.TempDir(), "clone"), "", "", "")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := client.Clone(context.Background()); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+
+	want := commitFiles(t, srcDir, src, map[string]string{"mem.json": `{"title": "Memory"}`})
+	got, err := client.FetchLatestCommit(context.Background())
+	if err != nil {
+		t.Fatalf("FetchLatestCommit() error = %v", err)
+	}
+	if got != want.String() {
+		t.Errorf("FetchLatestCommit() = %s, want %s", got, want)
+	}
+
+	fsys, err := client.TreeFS()
+	if err != nil {
+		t.Fatalf("TreeFS() error = %v", err)
+	}
+	if _, err := fs.Stat(fsys, "mem.json"); err != nil {
+		t.Errorf("fetched file missing from tree: %v", err)
+	}
+
+	info, err := client.GetCommitInfo()
+	if err != nil || info.Hash != want.String() {
+		t.Errorf("GetCommitInfo() = %+v, %v", info, err)
+	}
+}
+
+func TestClient_Checkout(t *testing.T) {
+	srcDir, src := initRepo(t)
+	first := commitFiles(t, srcDir, src, map[string]string{"cpu.json": `{"title": "CPU"}`})
+	second := commitFiles(t, srcDir, src, map[string]string{"cpu.json": `{"title": "CPU v2"}`})
+	third := commitFiles(t, srcDir, src, map[string]string{"mem.json": `{"title": "Memory"}`})
+
+	client, err := NewClient("file://"+srcDir, "main", filepath.Join(t.TempDir(), "clone"), "", "", "")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := client.Clone(context.Background()); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+
+	// The shallow clone only has the head, so both commits must be fetched
+	got, err := client.Checkout(context.Background(), PreviousCommit)
+	if err != nil || got != second.String() {
+		t.Fatalf("Checkout(previous) = %s, %v, want %s", got, err, second)
+	}
+	if client.Ref() != "commit:"+second.String() {
+		t.Errorf("Ref() = %s after checkout", client.Ref())
+	}
+	got, err = client.Checkout(context.Background(), first.String())
+	if err != nil || got != first.String() {
+		t.Fatalf("Checkout(%s) = %s, %v", first, got, err)
+	}
+	fsys, err := client.TreeFS()
+	if err != nil {
+		t.Fatalf("TreeFS() error = %v", err)
+	}
+	if _, err := fs.Stat(fsys, "mem.json"); err == nil {
+		t.Error("tree of the checked out commit contains a later file")
+	}
+
+	for _, rev := range []string{"", "abc1234", PreviousCommit} {
+		if _, err := client.Checkout(context.Background(), rev); err == nil {
+			t.Errorf("Checkout(%q) should fail", rev)
+		}
+	}
+
+	// Fetching returns to the branch
+	if got, err := client.FetchLatestCommit(context.Background()); err != nil || got != third.String() {
+		t.Errorf("FetchLatestCommit() = %s, %v, want %s", got, err, third)
+	}
+}
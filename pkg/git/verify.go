@@ -0,0 +1,251 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+// VerifyMode selects which commits must carry a trusted signature
+type VerifyMode string
+
+const (
+	VerifyOff  VerifyMode = "off"  // signatures are not checked
+	VerifyHead VerifyMode = "head" // the fetched commit must be signed
+	VerifyAll  VerifyMode = "all"  // every commit since the last verified one must be signed
+)
+
+// ParseVerifyMode parses a VerifyMode; an empty string is VerifyOff
+func ParseVerifyMode(s string) (VerifyMode, error) {
+	switch mode := VerifyMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "", VerifyOff:
+		return VerifyOff, nil
+	case VerifyHead, VerifyAll:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown signature verification mode %q (want off, head or all)", s)
+	}
+}
+
+// SignatureError reports a commit that is not signed by a trusted key
+type SignatureError struct {
+	Commit plumbing.Hash
+	Reason string
+}
+
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("commit %s %s", e.Commit, e.Reason)
+}
+
+const (
+	pgpKeyBegin = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	pgpKeyEnd   = "-----END PGP PUBLIC KEY BLOCK-----"
+	sshSigBegin = "-----BEGIN SSH SIGNATURE-----"
+
+	// sshSigNamespace is the namespace git uses when signing commits with SSH keys
+	sshSigNamespace = "git"
+)
+
+// Keyring holds the keys trusted to sign commits
+type Keyring struct {
+	pgp     string          // armored OpenPGP public keys
+	pgpKeys int             // number of OpenPGP keys in pgp
+	ssh     []ssh.PublicKey // SSH public keys
+}
+
+// LoadKeyring reads a keyring file, see ParseKeyring
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted keys: %w", err)
+	}
+	keyring, err := ParseKeyring(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted keys in %s: %w", path, err)
+	}
+	return keyring, nil
+}
+
+// ParseKeyring parses armored OpenPGP public key blocks and SSH public keys,
+// one per line in authorized_keys or allowed_signers format. Blank lines and
+// lines starting with # are ignored.
+func ParseKeyring(data []byte) (*Keyring, error) {
+	k := &Keyring{}
+	var pgp strings.Builder
+	inBlock := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == pgpKeyBegin:
+			inBlock = true
+			pgp.WriteString(line + "\n")
+		case inBlock:
+			pgp.WriteString(line + "\n")
+			inBlock = line != pgpKeyEnd
+		case line == "" || strings.HasPrefix(line, "#"):
+		default:
+			key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			k.ssh = append(k.ssh, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if inBlock {
+		return nil, errors.New("unterminated OpenPGP public key block")
+	}
+
+	if pgp.Len() > 0 {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(pgp.String()))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OpenPGP key: %w", err)
+		}
+		k.pgp, k.pgpKeys = pgp.String(), len(entities)
+	}
+	if k.pgpKeys == 0 && len(k.ssh) == 0 {
+		return nil, errors.New("no keys found")
+	}
+	return k, nil
+}
+
+// String summarizes the keyring for logs
+func (k *Keyring) String() string {
+	return fmt.Sprintf("%d OpenPGP and %d SSH key(s)", k.pgpKeys, len(k.ssh))
+}
+
+// Verify checks that commit carries a valid signature by a trusted key and
+// returns a description of the signing key
+func (k *Keyring) Verify(commit *object.Commit) (string, error) {
+	switch {
+	case commit.PGPSignature == "":
+		return "", &SignatureError{Commit: commit.Hash, Reason: "is not signed"}
+	case strings.HasPrefix(commit.PGPSignature, sshSigBegin):
+		return k.verifySSH(commit)
+	}
+
+	if k.pgpKeys == 0 {
+		return "", &SignatureError{Commit: commit.Hash, Reason: "is signed with OpenPGP but no OpenPGP keys are trusted"}
+	}
+	entity, err := commit.Verify(k.pgp)
+	if errors.Is(err, pgperrors.ErrUnknownIssuer) {
+		return "", &SignatureError{Commit: commit.Hash, Reason: "is signed by an untrusted OpenPGP key"}
+	}
+	if err != nil {
+		return "", &SignatureError{Commit: commit.Hash, Reason: fmt.Sprintf("has an invalid OpenPGP signature: %v", err)}
+	}
+	return "OpenPGP key " + entity.PrimaryKey.KeyIdString(), nil
+}
+
+// sshSignature is the SSHSIG blob of an armored SSH signature, following its "SSHSIG" magic preamble
+type sshSignature struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// verifySSH checks an SSH signature as created by "git commit -S" with gpg.format=ssh
+func (k *Keyring) verifySSH(commit *object.Commit) (string, error) {
+	invalid := func(format string, args ...any) error {
+		return &SignatureError{Commit: commit.Hash, Reason: "has an invalid SSH signature: " + fmt.Sprintf(format, args...)}
+	}
+
+	block, _ := pem.Decode([]byte(commit.PGPSignature))
+	if block == nil || block.Type != "SSH SIGNATURE" || !bytes.HasPrefix(block.Bytes, []byte("SSHSIG")) {
+		return "", invalid("malformed armor")
+	}
+	var sig sshSignature
+	if err := ssh.Unmarshal(block.Bytes[len("SSHSIG"):], &sig); err != nil {
+		return "", invalid("%v", err)
+	}
+	if sig.Version != 1 {
+		return "", invalid("unsupported version %d", sig.Version)
+	}
+	if sig.Namespace != sshSigNamespace {
+		return "", invalid("namespace %q is not %q", sig.Namespace, sshSigNamespace)
+	}
+
+	pub, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return "", invalid("%v", err)
+	}
+	if !k.trustsSSH(pub) {
+		return "", &SignatureError{Commit: commit.Hash, Reason: "is signed by an untrusted SSH key " + ssh.FingerprintSHA256(pub)}
+	}
+
+	var h hash.Hash
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return "", invalid("unsupported hash algorithm %q", sig.HashAlgorithm)
+	}
+	message, err := encodeWithoutSignature(commit)
+	if err != nil {
+		return "", err
+	}
+	h.Write(message)
+
+	signed := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{sig.Namespace, sig.Reserved, sig.HashAlgorithm, h.Sum(nil)})...)
+
+	var s ssh.Signature
+	if err := ssh.Unmarshal(sig.Signature, &s); err != nil {
+		return "", invalid("%v", err)
+	}
+	if err := pub.Verify(signed, &s); err != nil {
+		return "", invalid("%v", err)
+	}
+	return "SSH key " + ssh.FingerprintSHA256(pub), nil
+}
+
+func (k *Keyring) trustsSSH(pub ssh.PublicKey) bool {
+	for _, key := range k.ssh {
+		if bytes.Equal(key.Marshal(), pub.Marshal()) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeWithoutSignature returns the commit object as it was signed
+func encodeWithoutSignature(commit *object.Commit) ([]byte, error) {
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		return nil, err
+	}
+	r, err := encoded.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,247 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/crypto/ssh"
+)
+
+// pgpKey creates an OpenPGP key and returns it with its armored public key
+func pgpKey(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return entity, buf.String()
+}
+
+// sshKey creates an SSH signing key and returns it with its authorized_keys line
+func sshKey(t *testing.T) (ssh.Signer, string) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+}
+
+func pgpSigner(entity *openpgp.Entity) func(t *testing.T, message []byte) string {
+	return func(t *testing.T, message []byte) string {
+		var buf bytes.Buffer
+		if err := openpgp.ArmoredDetachSign(&buf, entity, bytes.NewReader(message), nil); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+}
+
+// sshSigner signs like "git commit -S" with gpg.format=ssh
+func sshSigner(signer ssh.Signer, namespace string) func(t *testing.T, message []byte) string {
+	return func(t *testing.T, message []byte) string {
+		digest := sha512.Sum512(message)
+		signed := append([]byte("SSHSIG"), ssh.Marshal(struct {
+			Namespace, Reserved, HashAlgorithm string
+			Hash                               []byte
+		}{namespace, "", "sha512", digest[:]})...)
+		sig, err := signer.Sign(rand.Reader, signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blob := append([]byte("SSHSIG"), ssh.Marshal(sshSignature{
+			Version:       1,
+			PublicKey:     signer.PublicKey().Marshal(),
+			Namespace:     namespace,
+			HashAlgorithm: "sha512",
+			Signature:     ssh.Marshal(sig),
+		})...)
+		return string(pem.EncodeToMemory(&pem.Block{Type: "SSH SIGNATURE", Bytes: blob}))
+	}
+}
+
+// signCommit replaces the commit at the tip of main with a signed copy
+func signCommit(t *testing.T, repo *gogit.Repository, hash plumbing.Hash, sign func(*testing.T, []byte) string) plumbing.Hash {
+	t.Helper()
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := encodeWithoutSignature(commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit.PGPSignature = sign(t, message)
+
+	obj := repo.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		t.Fatal(err)
+	}
+	signed, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("main"), signed)); err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestParseKeyring(t *testing.T) {
+	_, pgpPub := pgpKey(t)
+	_, sshPub := sshKey(t)
+
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{name: "mixed", data: "# release keys\n" + pgpPub + "\n" + sshPub, want: "1 OpenPGP and 1 SSH key(s)"},
+		{name: "allowed signers", data: "alice@example.com " + sshPub, want: "0 OpenPGP and 1 SSH key(s)"},
+		{name: "empty", data: "# nobody\n", wantErr: true},
+		{name: "invalid SSH key", data: "ssh-ed25519 not-base64\n", wantErr: true},
+		{name: "unterminated block", data: strings.SplitAfter(pgpPub, "\n")[0], wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := ParseKeyring([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKeyring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && keyring.String() != tt.want {
+				t.Errorf("ParseKeyring() = %s, want %s", keyring, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyring_Verify(t *testing.T) {
+	trustedPGP, pgpPub := pgpKey(t)
+	untrustedPGP, _ := pgpKey(t)
+	trustedSSH, sshPub := sshKey(t)
+	untrustedSSH, _ := sshKey(t)
+
+	keyring, err := ParseKeyring([]byte(pgpPub + "\n" + sshPub))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		sign    func(*testing.T, []byte) string
+		wantErr string
+	}{
+		{name: "unsigned", wantErr: "is not signed"},
+		{name: "trusted OpenPGP key", sign: pgpSigner(trustedPGP)},
+		{name: "untrusted OpenPGP key", sign: pgpSigner(untrustedPGP), wantErr: "untrusted OpenPGP key"},
+		{name: "trusted SSH key", sign: sshSigner(trustedSSH, "git")},
+		{name: "untrusted SSH key", sign: sshSigner(untrustedSSH, "git"), wantErr: "untrusted SSH key"},
+		{name: "SSH key for another namespace", sign: sshSigner(trustedSSH, "file"), wantErr: "namespace"},
+		{
+			name: "tampered commit",
+			sign: func(t *testing.T, message []byte) string {
+				return sshSigner(trustedSSH, "git")(t, append(message, "more"...))
+			},
+			wantErr: "invalid SSH signature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, repo := initRepo(t)
+			hash := commitFiles(t, dir, repo, map[string]string{"cpu.json": `{"title": "CPU"}`})
+			if tt.sign != nil {
+				hash = signCommit(t, repo, hash, tt.sign)
+			}
+			commit, err := repo.CommitObject(hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			signer, err := keyring.Verify(commit)
+			if tt.wantErr == "" {
+				if err != nil || signer == "" {
+					t.Errorf("Verify() = %q, %v", signer, err)
+				}
+				return
+			}
+			var sigErr *SignatureError
+			if !errors.As(err, &sigErr) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_Verification(t *testing.T) {
+	signer, sshPub := sshKey(t)
+	keyring, err := ParseKeyring([]byte(sshPub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := sshSigner(signer, "git")
+
+	for _, mode := range []VerifyMode{VerifyHead, VerifyAll} {
+		t.Run(string(mode), func(t *testing.T) {
+			srcDir, src := initRepo(t)
+			first := signCommit(t, src, commitFiles(t, srcDir, src, map[string]string{"cpu.json": `{"title": "CPU"}`}), sign)
+
+			client, err := NewClient("file://"+srcDir, "main", filepath.Join(t.TempDir(), "clone"), "", "", "")
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			client.SetVerification(mode, keyring)
+			if err := client.Clone(context.Background()); err != nil {
+				t.Fatalf("Clone() error = %v", err)
+			}
+
+			// An unsigned commit is refused and the last verified commit stays the head
+			commitFiles(t, srcDir, src, map[string]string{"mem.json": `{"title": "Memory"}`})
+			_, err = client.FetchLatestCommit(context.Background())
+			var sigErr *SignatureError
+			if !errors.As(err, &sigErr) {
+				t.Fatalf("FetchLatestCommit() error = %v, want a SignatureError", err)
+			}
+			if info, _ := client.GetCommitInfo(); info == nil || info.Hash != first.String() {
+				t.Errorf("head = %+v, want %s", info, first)
+			}
+
+			// A signed commit on top is only accepted when intermediate commits are not checked
+			last := signCommit(t, src, commitFiles(t, srcDir, src, map[string]string{"disk.json": `{"title": "Disk"}`}), sign)
+			got, err := client.FetchLatestCommit(context.Background())
+			if mode == VerifyHead && (err != nil || got != last.String()) {
+				t.Errorf("FetchLatestCommit() = %s, %v, want %s", got, err, last)
+			}
+			if mode == VerifyAll && !errors.As(err, &sigErr) {
+				t.Errorf("FetchLatestCommit() error = %v, want a SignatureError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// CommitURL links to a commit in the web UI of the repository at repoURL.
+// GitLab and Bitbucket commit paths are recognised by host name, other hosts
+// get the /commit/<hash> path used by GitHub, Gitea and Forgejo. Returns ""
+// when repoURL is not a remote repository.
+func CommitURL(repoURL, hash string) string {
+	base, path, ok := RepoWebURL(repoURL)
+	if !ok {
+		return ""
+	}
+	host := strings.ToLower(base)
+	switch {
+	case strings.Contains(host, "gitlab"):
+		return fmt.Sprintf("%s/%s/-/commit/%s", base, path, hash)
+	case strings.Contains(host, "bitbucket"):
+		return fmt.Sprintf("%s/%s/commits/%s", base, path, hash)
+	default:
+		return fmt.Sprintf("%s/%s/commit/%s", base, path, hash)
+	}
+}
+
+// RepoWebURL splits repoURL into the web UI base URL, e.g. https://github.com,
+// and the repository path without .git, e.g. org/repo. SSH URLs are assumed
+// to be served over HTTPS by the same host.
+func RepoWebURL(repoURL string) (base, path string, ok bool) {
+	ep, err := transport.NewEndpoint(repoURL)
+	if err != nil || ep.Host == "" {
+		return "", "", false
+	}
+
+	switch ep.Protocol {
+	case "http", "https":
+		base = ep.Protocol + "://" + ep.Host
+		if ep.Port != 0 && !(ep.Protocol == "http" && ep.Port == 80) && !(ep.Protocol == "https" && ep.Port == 443) {
+			base += fmt.Sprintf(":%d", ep.Port)
+		}
+	case "ssh", "git":
+		base = "https://" + ep.Host
+	default:
+		return "", "", false
+	}
+
+	path = strings.TrimSuffix(strings.Trim(ep.Path, "/"), ".git")
+	if path == "" {
+		return "", "", false
+	}
+	return base, path, true
+}
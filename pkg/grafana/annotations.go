@@ -0,0 +1,99 @@
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"grafana_git_sync/pkg/tracing"
+)
+
+// AnnotationMode selects where deployments are marked with an annotation
+type AnnotationMode string
+
+const (
+	AnnotationsOff       AnnotationMode = "off"       // no annotations
+	AnnotationsGlobal    AnnotationMode = "global"    // one organization-wide annotation per sync
+	AnnotationsDashboard AnnotationMode = "dashboard" // one annotation on each updated dashboard
+)
+
+// ParseAnnotationMode parses an AnnotationMode; an empty string is AnnotationsOff
+func ParseAnnotationMode(s string) (AnnotationMode, error) {
+	switch mode := AnnotationMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "", AnnotationsOff:
+		return AnnotationsOff, nil
+	case AnnotationsGlobal, AnnotationsDashboard:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown annotation mode %q (want off, global or dashboard)", s)
+	}
+}
+
+// Annotation is an event marked on dashboard graphs
+type Annotation struct {
+	DashboardUID string // empty for an organization-wide annotation
+	Time         time.Time
+	Tags         []string
+	Text         string // may contain HTML links
+}
+
+// CreateAnnotation posts an annotation and returns its ID
+func (c *Client) CreateAnnotation(ctx context.Context, a Annotation) (int, error) {
+	body := map[string]interface{}{
+		"time": a.Time.UnixMilli(),
+		"tags": a.Tags,
+		"text": a.Text,
+	}
+	if a.DashboardUID != "" {
+		body["dashboardUID"] = a.DashboardUID
+	}
+	if a.Tags == nil {
+		body["tags"] = []string{}
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal annotation JSON: %w", err)
+	}
+
+	ctx, span := tracer.Start(ctx, "grafana.annotation", trace.WithAttributes(tracing.DashboardUID.String(a.DashboardUID)))
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url+"/api/annotations", bytes.NewBuffer(data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	c.setAuth(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		err = fmt.Errorf("HTTP request failed: %w", err)
+		tracing.Fail(span, err)
+		return 0, err
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(tracing.HTTPStatus.Int(resp.StatusCode))
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		err := &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+		tracing.Fail(span, err)
+		return 0, err
+	}
+
+	var result struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return 0, fmt.Errorf("failed to decode annotation response: %w", err)
+	}
+	return result.ID, nil
+}
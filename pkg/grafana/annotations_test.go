@@ -0,0 +1,110 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseAnnotationMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    AnnotationMode
+		wantErr bool
+	}{
+		{mode: "", want: AnnotationsOff},
+		{mode: "off", want: AnnotationsOff},
+		{mode: "Global", want: AnnotationsGlobal},
+		{mode: "dashboard", want: AnnotationsDashboard},
+		{mode: "panel", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got, err := ParseAnnotationMode(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAnnotationMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAnnotationMode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_CreateAnnotation(t *testing.T) {
+	tests := []struct {
+		name         string
+		annotation   Annotation
+		statusCode   int
+		mockResponse string
+		wantBody     map[string]interface{}
+		expectID     int
+		expectError  bool
+	}{
+		{
+			name:         "dashboard annotation",
+			annotation:   Annotation{DashboardUID: "dash-uid", Time: time.UnixMilli(1700000000000), Tags: []string{"deploy"}, Text: "commit abc1234"},
+			statusCode:   200,
+			mockResponse: `{"message": "Annotation added", "id": 42}`,
+			wantBody:     map[string]interface{}{"dashboardUID": "dash-uid", "time": float64(1700000000000), "tags": []interface{}{"deploy"}, "text": "commit abc1234"},
+			expectID:     42,
+		},
+		{
+			name:         "global annotation",
+			annotation:   Annotation{Time: time.UnixMilli(1700000000000), Text: "commit abc1234"},
+			statusCode:   200,
+			mockResponse: `{"message": "Annotation added", "id": 7}`,
+			wantBody:     map[string]interface{}{"time": float64(1700000000000), "tags": []interface{}{}, "text": "commit abc1234"},
+			expectID:     7,
+		},
+		{
+			name:         "permission denied",
+			annotation:   Annotation{Text: "commit abc1234"},
+			statusCode:   403,
+			mockResponse: `{"message": "Permission denied"}`,
+			expectError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/annotations" || r.Method != "POST" {
+					t.Errorf("Expected POST /api/annotations, got %s %s", r.Method, r.URL.Path)
+				}
+				if tt.wantBody != nil {
+					var body map[string]interface{}
+					if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+						t.Fatal(err)
+					}
+					got, _ := json.Marshal(body)
+					want, _ := json.Marshal(tt.wantBody)
+					if string(got) != string(want) {
+						t.Errorf("body = %s, want %s", got, want)
+					}
+				}
+				w.WriteHeader(tt.statusCode)
+				w.Write([]byte(tt.mockResponse))
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL, "test-token", "", "")
+			id, err := client.CreateAnnotation(context.Background(), tt.annotation)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("CreateAnnotation() error = %v, expectError %v", err, tt.expectError)
+			}
+			var apiErr *APIError
+			if tt.expectError && (!errors.As(err, &apiErr) || apiErr.StatusCode != tt.statusCode) {
+				t.Errorf("CreateAnnotation() error = %v, want an APIError with status %d", err, tt.statusCode)
+			}
+			if id != tt.expectID {
+				t.Errorf("CreateAnnotation() id = %d, want %d", id, tt.expectID)
+			}
+		})
+	}
+}
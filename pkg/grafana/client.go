@@ -2,19 +2,29 @@ package grafana
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	gosync "sync"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"grafana_git_sync/pkg/sync"
+	"grafana_git_sync/pkg/tracing"
 )
 
+var tracer = otel.Tracer("grafana_git_sync/pkg/grafana")
+
 // Client handles Grafana API operations
 type Client struct {
 	url      string
+	authMu   gosync.RWMutex // guards token, user and password, which may be rotated
 	token    string
 	user     string
 	password string
@@ -32,53 +42,64 @@ func NewClient(url, token, user, password string) *Client {
 	}
 }
 
+// SetTransport sends API requests through rt, e.g. to apply a proxy or a custom CA bundle
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.client.Transport = rt
+}
+
 // WaitForReady waits until Grafana API is available
-func (c *Client) WaitForReady(timeout time.Duration) error {
-	log.Println("⏳ Waiting for Grafana API...")
+func (c *Client) WaitForReady(ctx context.Context, timeout time.Duration) error {
+	slog.Info("Waiting for Grafana API", "url", c.url)
 
 	deadline := time.Now().Add(timeout)
 	url := fmt.Sprintf("%s/api/health", c.url)
 
 	for time.Now().Before(deadline) {
-		resp, err := c.client.Get(url)
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create HTTP request: %w", err)
+		}
+		resp, err := c.client.Do(req)
 		if err == nil && resp.StatusCode == 200 {
 			resp.Body.Close()
-			log.Println("✅ Grafana API is ready")
+			slog.Info("Grafana API is ready")
 			return nil
 		}
 
 		if err != nil {
-			log.Printf("⚠️ Grafana not ready: %v", err)
+			slog.Warn("Grafana not ready", "error", err)
 		} else {
-			log.Printf("⚠️ Grafana returned status: %d", resp.StatusCode)
+			slog.Warn("Grafana not ready", "grafana_status", resp.StatusCode)
 			resp.Body.Close()
 		}
 
-		time.Sleep(2 * time.Second)
+		if err := sleepContext(ctx, 2*time.Second); err != nil {
+			return err
+		}
 	}
 
 	return fmt.Errorf("Grafana API did not become ready within %v", timeout)
 }
 
 // ValidateAuth validates that the provided credentials work
-func (c *Client) ValidateAuth() error {
-	log.Println("🔐 Validating Grafana credentials...")
+func (c *Client) ValidateAuth(ctx context.Context) error {
+	slog.Info("Validating Grafana credentials")
 
 	url := fmt.Sprintf("%s/api/health", c.url)
 
 	for i := 0; i < 30; i++ {
-		req, _ := http.NewRequest("GET", url, nil)
-		req.SetBasicAuth(c.user, c.password)
+		req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+		c.setBasicAuth(req)
 
 		resp, err := c.client.Do(req)
 		if err != nil {
-			log.Printf("⚠️ Network error: %v", err)
+			slog.Warn("Grafana request failed", "error", err)
 		} else {
 			body, _ := io.ReadAll(resp.Body)
 			resp.Body.Close()
 
 			if resp.StatusCode == 200 {
-				log.Println("✅ Grafana authentication OK")
+				slog.Info("Grafana authentication OK")
 				return nil
 			}
 
@@ -86,35 +107,37 @@ func (c *Client) ValidateAuth() error {
 				return fmt.Errorf("Grafana returned 401 Unauthorized — invalid credentials")
 			}
 
-			log.Printf("⚠️ Grafana auth returned %d: %s", resp.StatusCode, string(body))
+			slog.Warn("Grafana authentication failed", "grafana_status", resp.StatusCode, "body", string(body))
 		}
 
-		time.Sleep(2 * time.Second)
+		if err := sleepContext(ctx, 2*time.Second); err != nil {
+			return err
+		}
 	}
 
 	return fmt.Errorf("Grafana authentication check timed out")
 }
 
 // CreateServiceAccountToken creates or recreates a service account token
-func (c *Client) CreateServiceAccountToken(accountName, tokenName string) (string, error) {
+func (c *Client) CreateServiceAccountToken(ctx context.Context, accountName, tokenName string) (string, error) {
 	// Ensure service account exists
-	saID, err := c.ensureServiceAccount(accountName)
+	saID, err := c.ensureServiceAccount(ctx, accountName)
 	if err != nil {
 		return "", fmt.Errorf("cannot ensure service account: %w", err)
 	}
 
 	// Create or replace token
-	token, err := c.createOrReplaceSAToken(saID, tokenName)
+	token, err := c.createOrReplaceSAToken(ctx, saID, tokenName)
 	if err != nil {
 		return "", fmt.Errorf("cannot create service account token: %w", err)
 	}
 
 	// Validate token works
-	if err := c.waitForSAToken(token, 2*time.Minute); err != nil {
+	if err := c.waitForSAToken(ctx, token, 2*time.Minute); err != nil {
 		return "", fmt.Errorf("service account token is not ready: %w", err)
 	}
 
-	log.Println("✅ Service account token ready")
+	slog.Info("Service account token ready")
 	return token, nil
 }
 
@@ -131,17 +154,23 @@ func (c *Client) GetFolderIDByPath(folderPath string) int {
 }
 
 // CreateFolderTree creates a nested folder structure in Grafana
-func (c *Client) CreateFolderTree(folderPath string) (int, error) {
+func (c *Client) CreateFolderTree(ctx context.Context, folderPath string) (int, error) {
 	// Check cache first
 	if id, ok := c.folders[folderPath]; ok {
 		return id, nil
 	}
 
-	return c.createFolderRecursive(folderPath, "")
+	ctx, span := tracer.Start(ctx, "grafana.folder", trace.WithAttributes(tracing.Folder.String(folderPath)))
+	defer span.End()
+	id, err := c.createFolderRecursive(ctx, folderPath, "")
+	if err != nil {
+		tracing.Fail(span, err)
+	}
+	return id, err
 }
 
 // getFolderByTitle searches for an existing folder by title and optional parent UID
-func (c *Client) getFolderByTitle(title, parentUid string) (int, string, error) {
+func (c *Client) getFolderByTitle(ctx context.Context, title, parentUid string) (int, string, error) {
 	// Use folders API with parentUid parameter to get children of a specific folder
 	var url string
 	if parentUid == "" {
@@ -151,7 +180,7 @@ func (c *Client) getFolderByTitle(title, parentUid string) (int, string, error)
 		// Child folders - use parentUid parameter
 		url = fmt.Sprintf("%s/api/folders?parentUid=%s&limit=1000", c.url, parentUid)
 	}
-	req, _ := http.NewRequest("GET", url, nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	c.setAuth(req)
 
 	resp, err := c.client.Do(req)
@@ -194,18 +223,28 @@ func (c *Client) getFolderByTitle(title, parentUid string) (int, string, error)
 }
 
 // CreateFolderTreeFromNode creates a folder tree from a FolderNode structure
-func (c *Client) CreateFolderTreeFromNode(node *sync.FolderNode, parentUid string) error {
+func (c *Client) CreateFolderTreeFromNode(ctx context.Context, node *sync.FolderNode, parentUid string) (err error) {
+	ctx, span := tracer.Start(ctx, "grafana.folder", trace.WithAttributes(tracing.Folder.String(node.FullPath)))
+	defer func() {
+		if err != nil {
+			tracing.Fail(span, err)
+		}
+		span.SetAttributes(attribute.String("grafana.folder.uid", node.UID))
+		span.End()
+	}()
+
 	var currentUID string
 
 	// Check if folder already exists in Grafana (always check, even if cached)
-	existingID, existingUID, err := c.getFolderByTitle(node.Name, parentUid)
+	existingID, existingUID, err := c.getFolderByTitle(ctx, node.Name, parentUid)
 	if err != nil {
 		return fmt.Errorf("failed to check existing folder: %w", err)
 	}
 
 	if existingID > 0 {
 		// Folder already exists, use it
-		log.Printf("✅ Folder '%s' already exists (ID: %d, UID: %s, parent: %s)", node.Name, existingID, existingUID, parentUid)
+		span.SetAttributes(attribute.Bool("grafana.folder.existed", true))
+		slog.Debug("Folder already exists", "folder", node.FullPath, "folder_id", existingID, "folder_uid", existingUID, "parent_uid", parentUid)
 		node.ID = existingID
 		node.UID = existingUID
 		c.folders[node.FullPath] = existingID
@@ -218,7 +257,7 @@ func (c *Client) CreateFolderTreeFromNode(node *sync.FolderNode, parentUid strin
 		}
 		data, _ := json.Marshal(payload)
 
-		req, _ := http.NewRequest("POST", fmt.Sprintf("%s/api/folders", c.url), bytes.NewBuffer(data))
+		req, _ := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/folders", c.url), bytes.NewBuffer(data))
 		c.setAuth(req)
 		req.Header.Set("Content-Type", "application/json")
 
@@ -232,8 +271,8 @@ func (c *Client) CreateFolderTreeFromNode(node *sync.FolderNode, parentUid strin
 		if resp.StatusCode >= 300 {
 			// Check if error is "folder already exists"
 			if resp.StatusCode == 409 || resp.StatusCode == 412 {
-				log.Printf("⚠️ Folder '%s' already exists (conflict), fetching it...", node.Name)
-				existingID, existingUID, err := c.getFolderByTitle(node.Name, parentUid)
+				slog.Warn("Folder was created concurrently, fetching it", "folder", node.FullPath, "grafana_status", resp.StatusCode)
+				existingID, existingUID, err := c.getFolderByTitle(ctx, node.Name, parentUid)
 				if err != nil || existingID == 0 {
 					return fmt.Errorf("folder exists but cannot retrieve: %s", string(body))
 				}
@@ -252,7 +291,7 @@ func (c *Client) CreateFolderTreeFromNode(node *sync.FolderNode, parentUid strin
 			if err := json.Unmarshal(body, &created); err != nil {
 				return err
 			}
-			log.Printf("✅ Created folder '%s' (ID: %d, UID: %s, parent: %s)", node.Name, created.ID, created.UID, parentUid)
+			slog.Info("Created folder", "folder", node.FullPath, "folder_id", created.ID, "folder_uid", created.UID, "parent_uid", parentUid)
 			node.ID = created.ID
 			node.UID = created.UID
 			c.folders[node.FullPath] = node.ID
@@ -262,7 +301,7 @@ func (c *Client) CreateFolderTreeFromNode(node *sync.FolderNode, parentUid strin
 
 	// Process children with the current folder's UID as their parent
 	for _, child := range node.Children {
-		if err := c.CreateFolderTreeFromNode(child, currentUID); err != nil {
+		if err := c.CreateFolderTreeFromNode(ctx, child, currentUID); err != nil {
 			return err
 		}
 	}
@@ -270,12 +309,14 @@ func (c *Client) CreateFolderTreeFromNode(node *sync.FolderNode, parentUid strin
 }
 
 // UploadDashboard uploads a dashboard to Grafana
-func (c *Client) UploadDashboard(dashboard map[string]interface{}, folderID int) error {
-	return c.UploadDashboardWithVersion(dashboard, folderID, "")
+func (c *Client) UploadDashboard(ctx context.Context, dashboard map[string]interface{}, folderID int) error {
+	_, err := c.UploadDashboardWithVersion(ctx, dashboard, folderID, "")
+	return err
 }
 
-// UploadDashboardWithVersion uploads a dashboard with version metadata
-func (c *Client) UploadDashboardWithVersion(dashboard map[string]interface{}, folderID int, versionMessage string) error {
+// UploadDashboardWithVersion uploads a dashboard with version metadata and
+// reports whether Grafana created it rather than updating an existing one
+func (c *Client) UploadDashboardWithVersion(ctx context.Context, dashboard map[string]interface{}, folderID int, versionMessage string) (created bool, err error) {
 	body := map[string]interface{}{
 		"dashboard": dashboard,
 		"folderId":  folderID,
@@ -289,34 +330,64 @@ func (c *Client) UploadDashboardWithVersion(dashboard map[string]interface{}, fo
 
 	data, err := json.Marshal(body)
 	if err != nil {
-		return fmt.Errorf("failed to marshal dashboard JSON: %w", err)
+		return false, fmt.Errorf("failed to marshal dashboard JSON: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api/dashboards/db", c.url), bytes.NewBuffer(data))
+	ctx, span := tracer.Start(ctx, "grafana.upload_dashboard", trace.WithAttributes(
+		tracing.DashboardUID.String(fmt.Sprint(dashboard["uid"])),
+		attribute.Int("grafana.folder.id", folderID),
+	))
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/dashboards/db", c.url), bytes.NewBuffer(data))
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %w", err)
+		return false, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
 	c.setAuth(req)
 	req.Header.Set("Content-Type", "application/json")
 
+	start := time.Now()
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("HTTP request failed: %w", err)
+		err = fmt.Errorf("HTTP request failed: %w", err)
+		tracing.Fail(span, err)
+		return false, err
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(tracing.HTTPStatus.Int(resp.StatusCode))
 
+	respBody, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode >= 300 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("Grafana API error %d: %s", resp.StatusCode, string(respBody))
+		err := &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+		tracing.Fail(span, err)
+		return false, err
 	}
 
-	return nil
+	// The first version of a dashboard is the one Grafana just created
+	var result struct {
+		Version int `json:"version"`
+	}
+	json.Unmarshal(respBody, &result)
+	created = result.Version == 1
+
+	slog.Debug("Dashboard uploaded", "dashboard_uid", dashboard["uid"], "grafana_status", resp.StatusCode, "created", created, "duration", time.Since(start))
+	return created, nil
 }
 
-func (c *Client) ensureServiceAccount(accountName string) (string, error) {
-	req, _ := http.NewRequest("GET", c.url+"/api/serviceaccounts/search", nil)
-	req.SetBasicAuth(c.user, c.password)
+// APIError is an error response from the Grafana API
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Grafana API error %d: %s", e.StatusCode, e.Body)
+}
+
+func (c *Client) ensureServiceAccount(ctx context.Context, accountName string) (string, error) {
+	req, _ := http.NewRequestWithContext(ctx, "GET", c.url+"/api/serviceaccounts/search", nil)
+	c.setBasicAuth(req)
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to list service accounts: %w", err)
@@ -342,8 +413,8 @@ func (c *Client) ensureServiceAccount(accountName string) (string, error) {
 
 	// Create new service account
 	payload := fmt.Sprintf(`{"name":"%s","role":"Admin"}`, accountName)
-	reqCreate, _ := http.NewRequest("POST", c.url+"/api/serviceaccounts", bytes.NewBuffer([]byte(payload)))
-	reqCreate.SetBasicAuth(c.user, c.password)
+	reqCreate, _ := http.NewRequestWithContext(ctx, "POST", c.url+"/api/serviceaccounts", bytes.NewBuffer([]byte(payload)))
+	c.setBasicAuth(reqCreate)
 	reqCreate.Header.Set("Content-Type", "application/json")
 
 	respCreate, err := c.client.Do(reqCreate)
@@ -360,14 +431,14 @@ func (c *Client) ensureServiceAccount(accountName string) (string, error) {
 		return "", fmt.Errorf("failed to parse created service account: %w", err)
 	}
 
-	log.Println("✅ Service account created:", accountName)
+	slog.Info("Service account created", "service_account", accountName)
 	return fmt.Sprintf("%d", created.ID), nil
 }
 
-func (c *Client) createOrReplaceSAToken(saID, tokenName string) (string, error) {
+func (c *Client) createOrReplaceSAToken(ctx context.Context, saID, tokenName string) (string, error) {
 	// List existing tokens
-	reqTokens, _ := http.NewRequest("GET", fmt.Sprintf("%s/api/serviceaccounts/%s/tokens", c.url, saID), nil)
-	reqTokens.SetBasicAuth(c.user, c.password)
+	reqTokens, _ := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/serviceaccounts/%s/tokens", c.url, saID), nil)
+	c.setBasicAuth(reqTokens)
 	respTokens, err := c.client.Do(reqTokens)
 	if err != nil {
 		return "", fmt.Errorf("failed to list tokens: %w", err)
@@ -386,14 +457,14 @@ func (c *Client) createOrReplaceSAToken(saID, tokenName string) (string, error)
 	// Delete old token if exists
 	for _, t := range tokensResp {
 		if t.Name == tokenName {
-			delReq, _ := http.NewRequest("DELETE", fmt.Sprintf("%s/api/serviceaccounts/%s/tokens/%d", c.url, saID, t.ID), nil)
-			delReq.SetBasicAuth(c.user, c.password)
+			delReq, _ := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("%s/api/serviceaccounts/%s/tokens/%d", c.url, saID, t.ID), nil)
+			c.setBasicAuth(delReq)
 			respDel, err := c.client.Do(delReq)
 			if err != nil {
-				log.Printf("⚠️ Failed to delete old token %s: %v", tokenName, err)
+				slog.Warn("Failed to delete old token", "token", tokenName, "error", err)
 			} else {
 				respDel.Body.Close()
-				log.Println("🗑️ Old token deleted:", tokenName)
+				slog.Info("Old token deleted", "token", tokenName)
 			}
 			break
 		}
@@ -401,8 +472,8 @@ func (c *Client) createOrReplaceSAToken(saID, tokenName string) (string, error)
 
 	// Create new token
 	payload := fmt.Sprintf(`{"name":"%s"}`, tokenName)
-	reqCreate, _ := http.NewRequest("POST", fmt.Sprintf("%s/api/serviceaccounts/%s/tokens", c.url, saID), bytes.NewBuffer([]byte(payload)))
-	reqCreate.SetBasicAuth(c.user, c.password)
+	reqCreate, _ := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/serviceaccounts/%s/tokens", c.url, saID), bytes.NewBuffer([]byte(payload)))
+	c.setBasicAuth(reqCreate)
 	reqCreate.Header.Set("Content-Type", "application/json")
 
 	respCreate, err := c.client.Do(reqCreate)
@@ -420,20 +491,20 @@ func (c *Client) createOrReplaceSAToken(saID, tokenName string) (string, error)
 		return "", fmt.Errorf("failed to parse created token: %w", err)
 	}
 
-	log.Println("✅ Token created:", tokenName)
+	slog.Info("Token created", "token", tokenName)
 	return createdToken.Key, nil
 }
 
-func (c *Client) waitForSAToken(token string, timeout time.Duration) error {
+func (c *Client) waitForSAToken(ctx context.Context, token string, timeout time.Duration) error {
 	url := c.url + "/api/folders"
 	deadline := time.Now().Add(timeout)
 
 	for time.Now().Before(deadline) {
-		req, _ := http.NewRequest("GET", url, nil)
+		req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 		req.Header.Set("Authorization", "Bearer "+token)
 		resp, err := c.client.Do(req)
 		if err != nil {
-			log.Printf("⚠️ Grafana request failed: %v", err)
+			slog.Warn("Grafana request failed", "error", err)
 		} else {
 			body, _ := io.ReadAll(resp.Body)
 			resp.Body.Close()
@@ -443,17 +514,19 @@ func (c *Client) waitForSAToken(token string, timeout time.Duration) error {
 			} else if resp.StatusCode == 401 || resp.StatusCode == 403 {
 				return fmt.Errorf("service account token unauthorized: status %d", resp.StatusCode)
 			} else {
-				log.Printf("⚠️ Grafana responded with %d, body: %s", resp.StatusCode, string(body))
+				slog.Warn("Service account token not ready", "grafana_status", resp.StatusCode, "body", string(body))
 			}
 		}
 
-		time.Sleep(1 * time.Second)
+		if err := sleepContext(ctx, 1*time.Second); err != nil {
+			return err
+		}
 	}
 
 	return fmt.Errorf("service account token not ready within %v", timeout)
 }
 
-func (c *Client) createFolderRecursive(folderPath, parentUID string) (int, error) {
+func (c *Client) createFolderRecursive(ctx context.Context, folderPath, parentUID string) (int, error) {
 	parts := splitFolderPath(folderPath)
 	if len(parts) == 0 {
 		return 0, fmt.Errorf("empty folder path")
@@ -471,14 +544,14 @@ func (c *Client) createFolderRecursive(folderPath, parentUID string) (int, error
 		}
 
 		// Always check if folder exists in Grafana (with correct parent)
-		existingID, existingUID, err := c.getFolderByTitle(name, currentUID)
+		existingID, existingUID, err := c.getFolderByTitle(ctx, name, currentUID)
 		if err != nil {
 			return 0, fmt.Errorf("failed to check existing folder: %w", err)
 		}
 
 		if existingID > 0 {
 			// Folder already exists, use it
-			log.Printf("✅ Folder '%s' already exists (ID: %d, UID: %s, parent: %s)", name, existingID, existingUID, currentUID)
+			slog.Debug("Folder already exists", "folder", currentPath, "folder_id", existingID, "folder_uid", existingUID, "parent_uid", currentUID)
 			folderID = existingID
 			currentUID = existingUID
 			c.folders[currentPath] = folderID
@@ -492,7 +565,7 @@ func (c *Client) createFolderRecursive(folderPath, parentUID string) (int, error
 		}
 		data, _ := json.Marshal(payload)
 
-		req, _ := http.NewRequest("POST", fmt.Sprintf("%s/api/folders", c.url), bytes.NewBuffer(data))
+		req, _ := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/folders", c.url), bytes.NewBuffer(data))
 		c.setAuth(req)
 		req.Header.Set("Content-Type", "application/json")
 
@@ -506,8 +579,8 @@ func (c *Client) createFolderRecursive(folderPath, parentUID string) (int, error
 		if resp.StatusCode >= 300 {
 			// Check if error is "folder already exists"
 			if resp.StatusCode == 409 || resp.StatusCode == 412 {
-				log.Printf("⚠️ Folder '%s' already exists (conflict), fetching it...", name)
-				existingID, existingUID, err := c.getFolderByTitle(name, currentUID)
+				slog.Warn("Folder was created concurrently, fetching it", "folder", currentPath, "grafana_status", resp.StatusCode)
+				existingID, existingUID, err := c.getFolderByTitle(ctx, name, currentUID)
 				if err != nil || existingID == 0 {
 					return 0, fmt.Errorf("folder exists but cannot retrieve: %s", string(body))
 				}
@@ -527,7 +600,7 @@ func (c *Client) createFolderRecursive(folderPath, parentUID string) (int, error
 			return 0, err
 		}
 
-		log.Printf("✅ Created folder '%s' (ID: %d, UID: %s, parent: %s)", name, created.ID, created.UID, currentUID)
+		slog.Info("Created folder", "folder", currentPath, "folder_id", created.ID, "folder_uid", created.UID, "parent_uid", currentUID)
 		folderID = created.ID
 		currentUID = created.UID
 		c.folders[currentPath] = folderID
@@ -536,14 +609,51 @@ func (c *Client) createFolderRecursive(folderPath, parentUID string) (int, error
 	return folderID, nil
 }
 
+// sleepContext pauses for d or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SetToken replaces the service account token used for API requests
+func (c *Client) SetToken(token string) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.token = token
+}
+
+// SetBasicAuth replaces the admin credentials used for basic auth requests
+func (c *Client) SetBasicAuth(user, password string) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.user = user
+	c.password = password
+}
+
 func (c *Client) setAuth(req *http.Request) {
-	if c.token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.token)
+	c.authMu.RLock()
+	token := c.token
+	c.authMu.RUnlock()
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
 	} else {
-		req.SetBasicAuth(c.user, c.password)
+		c.setBasicAuth(req)
 	}
 }
 
+func (c *Client) setBasicAuth(req *http.Request) {
+	c.authMu.RLock()
+	defer c.authMu.RUnlock()
+	req.SetBasicAuth(c.user, c.password)
+}
+
 func splitFolderPath(path string) []string {
 	if path == "" || path == "." {
 		return nil
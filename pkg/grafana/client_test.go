@@ -1,9 +1,12 @@
 package grafana
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestNewClient(t *testing.T) {
@@ -82,7 +85,7 @@ func TestClient_GetFolderByTitle(t *testing.T) {
 			defer server.Close()
 
 			client := NewClient(server.URL, "test-token", "", "")
-			id, uid, err := client.getFolderByTitle(tt.title, tt.parentUid)
+			id, uid, err := client.getFolderByTitle(context.Background(), tt.title, tt.parentUid)
 
 			if (err != nil) != tt.expectError {
 				t.Errorf("getFolderByTitle() error = %v, expectError %v", err, tt.expectError)
@@ -103,14 +106,21 @@ func TestClient_UploadDashboard(t *testing.T) {
 		name         string
 		statusCode   int
 		mockResponse string
+		expectCreate bool
 		expectError  bool
 	}{
 		{
 			name:         "successful upload",
 			statusCode:   200,
-			mockResponse: `{"status": "success", "uid": "dash-uid"}`,
+			mockResponse: `{"status": "success", "uid": "dash-uid", "version": 3}`,
 			expectError:  false,
 		},
+		{
+			name:         "new dashboard",
+			statusCode:   200,
+			mockResponse: `{"status": "success", "uid": "dash-uid", "version": 1}`,
+			expectCreate: true,
+		},
 		{
 			name:         "API error",
 			statusCode:   400,
@@ -140,9 +150,12 @@ func TestClient_UploadDashboard(t *testing.T) {
 				},
 			}
 
-			err := client.UploadDashboard(dashboard, 1)
+			created, err := client.UploadDashboardWithVersion(context.Background(), dashboard, 1, "")
 			if (err != nil) != tt.expectError {
-				t.Errorf("UploadDashboard() error = %v, expectError %v", err, tt.expectError)
+				t.Errorf("UploadDashboardWithVersion() error = %v, expectError %v", err, tt.expectError)
+			}
+			if created != tt.expectCreate {
+				t.Errorf("UploadDashboardWithVersion() created = %v, want %v", created, tt.expectCreate)
 			}
 		})
 	}
@@ -243,7 +256,7 @@ func TestClient_CreateFolder(t *testing.T) {
 			defer server.Close()
 
 			client := NewClient(server.URL, "test-token", "", "")
-			_, err := client.createFolderRecursive("test-folder", "")
+			_, err := client.createFolderRecursive(context.Background(), "test-folder", "")
 
 			if (err != nil) != tt.expectError {
 				t.Errorf("createFolderRecursive() error = %v, expectError %v", err, tt.expectError)
@@ -251,3 +264,82 @@ func TestClient_CreateFolder(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_WaitForReady_Cancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client := NewClient(server.URL, "test-token", "", "")
+	err := client.WaitForReady(ctx, time.Minute)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitForReady() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestClient_SetToken(t *testing.T) {
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "old-token", "", "")
+	client.SetToken("new-token")
+
+	if err := client.UploadDashboard(context.Background(), map[string]interface{}{"title": "Test"}, 0); err != nil {
+		t.Fatalf("UploadDashboard() error = %v", err)
+	}
+	if gotAuth != "Bearer new-token" {
+		t.Errorf("Authorization = %q, want rotated token", gotAuth)
+	}
+}
+
+func TestClient_SetAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		user     string
+		password string
+		wantAuth string
+	}{
+		{"token", "secret", "admin", "pass", "Bearer secret"},
+		{"basic auth without a token", "", "admin", "pass", "Basic YWRtaW46cGFzcw=="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient("http://localhost:3000", tt.token, tt.user, tt.password)
+			req, _ := http.NewRequest("GET", "http://localhost:3000/api/health", nil)
+			client.setAuth(req)
+			if got := req.Header.Get("Authorization"); got != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", got, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestClient_SetBasicAuth(t *testing.T) {
+	var user, pass string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ = r.BasicAuth()
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	// Without a token every request falls back to basic auth
+	client := NewClient(server.URL, "", "admin", "old")
+	client.SetBasicAuth("admin", "new")
+
+	if err := client.UploadDashboard(context.Background(), map[string]interface{}{"title": "Test"}, 0); err != nil {
+		t.Fatalf("UploadDashboard() error = %v", err)
+	}
+	if user != "admin" || pass != "new" {
+		t.Errorf("basic auth = %q/%q, want rotated password", user, pass)
+	}
+}
@@ -0,0 +1,136 @@
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DashboardState is a dashboard as currently stored in Grafana
+type DashboardState struct {
+	Dashboard   map[string]interface{}
+	FolderUID   string // empty for the General folder
+	FolderTitle string
+	Version     int
+}
+
+// Permission is an access rule on a dashboard. Inherited rules come from the
+// folder and are not set on the dashboard itself.
+type Permission struct {
+	UserID     int    `json:"userId,omitempty"`
+	TeamID     int    `json:"teamId,omitempty"`
+	Role       string `json:"role,omitempty"`
+	Permission int    `json:"permission"` // 1 view, 2 edit, 4 admin
+	Inherited  bool   `json:"inherited,omitempty"`
+}
+
+// GetDashboard returns the dashboard with uid, or nil if it does not exist
+func (c *Client) GetDashboard(ctx context.Context, uid string) (*DashboardState, error) {
+	var result struct {
+		Dashboard map[string]interface{} `json:"dashboard"`
+		Meta      struct {
+			FolderUID   string `json:"folderUid"`
+			FolderTitle string `json:"folderTitle"`
+			Version     int    `json:"version"`
+		} `json:"meta"`
+	}
+	err := c.doJSON(ctx, "GET", "/api/dashboards/uid/"+url.PathEscape(uid), nil, &result)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &DashboardState{
+		Dashboard:   result.Dashboard,
+		FolderUID:   result.Meta.FolderUID,
+		FolderTitle: result.Meta.FolderTitle,
+		Version:     result.Meta.Version,
+	}, nil
+}
+
+// GetFolderPath returns the slash-separated titles from the root folder down
+// to the folder with uid, e.g. "Team A/api", as accepted by CreateFolderTree
+func (c *Client) GetFolderPath(ctx context.Context, uid string) (string, error) {
+	var folder struct {
+		Title   string `json:"title"`
+		Parents []struct {
+			Title string `json:"title"`
+		} `json:"parents"` // only returned with nested folders
+	}
+	if err := c.doJSON(ctx, "GET", "/api/folders/"+url.PathEscape(uid), nil, &folder); err != nil {
+		return "", err
+	}
+	titles := make([]string, 0, len(folder.Parents)+1)
+	for _, p := range folder.Parents {
+		titles = append(titles, p.Title)
+	}
+	return strings.Join(append(titles, folder.Title), "/"), nil
+}
+
+// GetDashboardPermissions returns the access rules of the dashboard with uid
+func (c *Client) GetDashboardPermissions(ctx context.Context, uid string) ([]Permission, error) {
+	var permissions []Permission
+	if err := c.doJSON(ctx, "GET", "/api/dashboards/uid/"+url.PathEscape(uid)+"/permissions", nil, &permissions); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// SetDashboardPermissions replaces the access rules of the dashboard with uid.
+// Inherited rules are skipped.
+func (c *Client) SetDashboardPermissions(ctx context.Context, uid string, permissions []Permission) error {
+	items := []Permission{}
+	for _, p := range permissions {
+		if !p.Inherited {
+			items = append(items, p)
+		}
+	}
+	body := map[string]interface{}{"items": items}
+	return c.doJSON(ctx, "POST", "/api/dashboards/uid/"+url.PathEscape(uid)+"/permissions", body, nil)
+}
+
+// doJSON sends body as JSON and decodes the response into out; either may be nil
+func (c *Client) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request JSON: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	c.setAuth(req)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_GetDashboard(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/dashboards/uid/cpu":
+			w.Write([]byte(`{"dashboard": {"uid": "cpu", "title": "CPU"}, "meta": {"folderUid": "team-a", "folderTitle": "Team A", "version": 7}}`))
+		case "/api/folders/api":
+			w.Write([]byte(`{"uid": "api", "title": "api", "parents": [{"uid": "team-a", "title": "Team A"}]}`))
+		default:
+			http.Error(w, `{"message":"Dashboard not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token", "", "")
+
+	got, err := client.GetDashboard(context.Background(), "cpu")
+	if err != nil {
+		t.Fatalf("GetDashboard() error = %v", err)
+	}
+	if got.Dashboard["title"] != "CPU" || got.FolderUID != "team-a" || got.FolderTitle != "Team A" || got.Version != 7 {
+		t.Errorf("GetDashboard() = %+v", got)
+	}
+
+	if got, err := client.GetDashboard(context.Background(), "missing"); got != nil || err != nil {
+		t.Errorf("GetDashboard(missing) = %+v, %v, want nil, nil", got, err)
+	}
+
+	if path, err := client.GetFolderPath(context.Background(), "api"); err != nil || path != "Team A/api" {
+		t.Errorf("GetFolderPath() = %q, %v, want Team A/api", path, err)
+	}
+}
+
+func TestClient_DashboardPermissions(t *testing.T) {
+	var posted struct {
+		Items []Permission `json:"items"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/dashboards/uid/cpu/permissions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Method == "GET" {
+			w.Write([]byte(`[{"role": "Viewer", "permission": 1, "inherited": true}, {"teamId": 3, "permission": 2}]`))
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&posted); err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(`{"message":"Dashboard permissions updated"}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token", "", "")
+
+	permissions, err := client.GetDashboardPermissions(context.Background(), "cpu")
+	if err != nil || len(permissions) != 2 {
+		t.Fatalf("GetDashboardPermissions() = %+v, %v", permissions, err)
+	}
+	if err := client.SetDashboardPermissions(context.Background(), "cpu", permissions); err != nil {
+		t.Fatalf("SetDashboardPermissions() error = %v", err)
+	}
+	if len(posted.Items) != 1 || posted.Items[0].TeamID != 3 || posted.Items[0].Permission != 2 {
+		t.Errorf("posted %+v, want only the team permission", posted.Items)
+	}
+}
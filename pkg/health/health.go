@@ -1,8 +1,11 @@
 package health
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
@@ -15,7 +18,33 @@ type Status struct {
 	GrafanaHealthy bool      `json:"grafana_healthy"`
 	GitSyncHealthy bool      `json:"git_sync_healthy"`
 	LastSyncTime   time.Time `json:"last_sync_time,omitempty"`
+	LastAttempt    time.Time `json:"last_attempt,omitempty"` // end of the last sync run, whatever its outcome
+	AttemptFailed  bool      `json:"last_attempt_failed"`    // whether that run failed or was blocked, see last_error
+	LastHeartbeat  time.Time `json:"last_heartbeat,omitempty"`
+	Started        bool      `json:"started"`
+	Ready          bool      `json:"ready"`
+	Stale          bool      `json:"stale,omitempty"`
+	Paused         bool      `json:"paused"`
+	PausedUntil    time.Time `json:"paused_until,omitempty"`
+	PinnedCommit   string    `json:"pinned_commit,omitempty"` // commit rolled back to; polls stay paused until resumed
 	LastError      string    `json:"last_error,omitempty"`
+	ConfigError    string    `json:"config_error,omitempty"`    // last failed config reload; the previous config stays in effect
+	SignatureError string    `json:"signature_error,omitempty"` // why the fetched commit was refused; the last verified commit stays synced
+	SyncBlocked    string    `json:"sync_blocked,omitempty"`    // why the safety thresholds stopped the last run; nothing was applied
+	GitRef         string    `json:"git_ref,omitempty"`         // revision the ref selector resolved to, e.g. "tag:v2.1.0"
+	GitCommit      string    `json:"git_commit,omitempty"`      // commit of the last fetch
+}
+
+// Options configures the thresholds used by the probe endpoints
+type Options struct {
+	// PollInterval is the expected time between sync loop iterations
+	PollInterval time.Duration
+	// StaleAfterPolls marks the service unhealthy when no sync has succeeded
+	// for this many poll intervals. Zero disables the check.
+	StaleAfterPolls int
+	// HeartbeatTimeout fails liveness when the sync loop has not reported a
+	// heartbeat for this long. Zero disables the watchdog.
+	HeartbeatTimeout time.Duration
 }
 
 // Checker manages health check state
@@ -24,14 +53,36 @@ type Checker struct {
 	grafanaHealthy bool
 	gitSyncHealthy bool
 	lastSyncTime   time.Time
+	lastAttempt    time.Time
+	attemptFailed  bool
 	lastError      string
+	configError    string
+	signatureError string
+	syncBlocked    string
+	gitRef         string
+	gitCommit      string
+	lastHeartbeat  time.Time
+	startedAt      time.Time
+	opts           Options
+	paused         bool
+	pausedUntil    time.Time
+	pinnedCommit   string
+	resources      map[string]ResourceStatus
+	routes         map[string]http.Handler
+	server         *http.Server
 }
 
 // NewChecker creates a new health checker
 func NewChecker() *Checker {
+	return NewCheckerWithOptions(Options{})
+}
+
+// NewCheckerWithOptions creates a new health checker with probe thresholds
+func NewCheckerWithOptions(opts Options) *Checker {
 	return &Checker{
 		grafanaHealthy: false,
 		gitSyncHealthy: false,
+		opts:           opts,
 	}
 }
 
@@ -56,6 +107,50 @@ func (c *Checker) SetLastSync(t time.Time) {
 	c.lastSyncTime = t
 }
 
+// SetLastAttempt records the end of a sync run and whether it failed or was blocked
+func (c *Checker) SetLastAttempt(t time.Time, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastAttempt = t
+	c.attemptFailed = failed
+}
+
+// SetStarted marks the startup phase (Grafana ready, repository cloned) as complete
+func (c *Checker) SetStarted() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.startedAt.IsZero() {
+		c.startedAt = time.Now()
+	}
+}
+
+// Heartbeat records that the sync loop is still iterating
+func (c *Checker) Heartbeat() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastHeartbeat = time.Now()
+}
+
+// SetPaused records whether the poll loop is paused and, if set, when it resumes automatically
+func (c *Checker) SetPaused(paused bool, until time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = paused
+	c.pausedUntil = until
+}
+
+// SetPinned records the commit the poll loop is pinned to after a rollback, "" when not pinned
+func (c *Checker) SetPinned(commit string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pinnedCommit = commit
+}
+
+// isPaused must be called with mu held
+func (c *Checker) isPaused(now time.Time) bool {
+	return c.paused && (c.pausedUntil.IsZero() || now.Before(c.pausedUntil))
+}
+
 // SetLastError updates the last error message
 func (c *Checker) SetLastError(err string) {
 	c.mu.Lock()
@@ -63,29 +158,140 @@ func (c *Checker) SetLastError(err string) {
 	c.lastError = err
 }
 
+// SetConfigError records why the last config reload was rejected, or clears it when empty
+func (c *Checker) SetConfigError(err string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.configError = err
+}
+
+// SetSignatureError records why a fetched commit was refused by signature
+// verification, or clears it when empty
+func (c *Checker) SetSignatureError(err string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.signatureError = err
+}
+
+// SetSyncBlocked records why the safety thresholds stopped a run, or clears it when empty
+func (c *Checker) SetSyncBlocked(reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.syncBlocked = reason
+}
+
+// SetRevision records the resolved Git ref and commit of the last fetch
+func (c *Checker) SetRevision(ref, commit string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gitRef = ref
+	c.gitCommit = commit
+}
+
+// SetOptions replaces the probe thresholds, e.g. after a config reload
+func (c *Checker) SetOptions(opts Options) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.opts = opts
+}
+
 // GetStatus returns current health status
 func (c *Checker) GetStatus() Status {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
+	now := time.Now()
+	stale := c.isStale(now)
+	paused := c.isPaused(now)
+
 	status := "healthy"
-	if !c.grafanaHealthy || !c.gitSyncHealthy {
+	if !c.grafanaHealthy || !c.gitSyncHealthy || c.syncBlocked != "" {
 		status = "degraded"
 	}
-	if !c.grafanaHealthy && !c.gitSyncHealthy {
+	if (!c.grafanaHealthy && !c.gitSyncHealthy) || stale {
 		status = "unhealthy"
 	}
 
 	return Status{
 		Status:         status,
-		Timestamp:      time.Now(),
+		Timestamp:      now,
 		GrafanaHealthy: c.grafanaHealthy,
 		GitSyncHealthy: c.gitSyncHealthy,
 		LastSyncTime:   c.lastSyncTime,
+		LastAttempt:    c.lastAttempt,
+		AttemptFailed:  c.attemptFailed,
+		LastHeartbeat:  c.lastHeartbeat,
+		Started:        !c.startedAt.IsZero(),
+		Ready:          !c.lastAttempt.IsZero(),
+		Stale:          stale,
+		Paused:         paused,
+		PausedUntil:    c.pausedUntil,
+		PinnedCommit:   c.pinnedCommit,
 		LastError:      c.lastError,
+		ConfigError:    c.configError,
+		SignatureError: c.signatureError,
+		SyncBlocked:    c.syncBlocked,
+		GitRef:         c.gitRef,
+		GitCommit:      c.gitCommit,
 	}
 }
 
+// isStale reports whether no sync has succeeded for StaleAfterPolls poll intervals.
+// Before the first successful sync the startup time is used as the reference.
+func (c *Checker) isStale(now time.Time) bool {
+	// A paused loop is not expected to sync
+	if c.opts.StaleAfterPolls <= 0 || c.opts.PollInterval <= 0 || c.isPaused(now) {
+		return false
+	}
+	ref := c.lastSyncTime
+	if ref.IsZero() {
+		ref = c.startedAt
+	}
+	if ref.IsZero() {
+		return false
+	}
+	return now.Sub(ref) > time.Duration(c.opts.StaleAfterPolls)*c.opts.PollInterval
+}
+
+// Live reports whether the sync loop is still making progress
+func (c *Checker) Live() (bool, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// Before the loop starts, hung startups are caught by the startup probe
+	if c.opts.HeartbeatTimeout <= 0 || c.lastHeartbeat.IsZero() {
+		return true, ""
+	}
+	if since := time.Since(c.lastHeartbeat); since > c.opts.HeartbeatTimeout {
+		return false, fmt.Sprintf("no sync loop heartbeat for %s", since.Round(time.Second))
+	}
+	return true, ""
+}
+
+// Ready reports whether the first sync run has finished. A blocked or failed
+// run counts too: its outcome is reported on /healthz and /metrics, and the
+// pod must not stay out of service until someone fixes the repository.
+func (c *Checker) Ready() (bool, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.lastAttempt.IsZero() {
+		return false, "initial sync has not finished"
+	}
+	return true, ""
+}
+
+// Started reports whether the startup phase has completed
+func (c *Checker) Started() (bool, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.startedAt.IsZero() {
+		return false, "waiting for Grafana and the initial clone"
+	}
+	return true, ""
+}
+
 // Handler returns an HTTP handler for health checks
 func (c *Checker) Handler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -104,18 +310,83 @@ func (c *Checker) Handler() http.HandlerFunc {
 		}
 
 		if err := json.NewEncoder(w).Encode(status); err != nil {
-			log.Printf("Failed to encode health status: %v", err)
+			slog.Error("Failed to encode health status", "error", err)
 		}
 	}
 }
 
-// StartServer starts the health check HTTP server
+// probeResult is the response body of the probe endpoints
+type probeResult struct {
+	Status string `json:"status"` // "ok" or "failed"
+	Reason string `json:"reason,omitempty"`
+}
+
+// probeHandler returns an HTTP handler that answers 200 when check passes and 503 otherwise
+func probeHandler(check func() (bool, string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ok, reason := check()
+
+		w.Header().Set("Content-Type", "application/json")
+		result := probeResult{Status: "ok"}
+		if ok {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			result = probeResult{Status: "failed", Reason: reason}
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			slog.Error("Failed to encode probe result", "error", err)
+		}
+	}
+}
+
+// Handle registers an additional handler on the health check server.
+// It must be called before StartServer.
+func (c *Checker) Handle(pattern string, handler http.Handler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.routes == nil {
+		c.routes = make(map[string]http.Handler)
+	}
+	c.routes[pattern] = handler
+}
+
+// StartServer starts the health check HTTP server and blocks until it is shut down
 func (c *Checker) StartServer(addr string) error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", c.Handler())
 	mux.HandleFunc("/health", c.Handler()) // Alternative endpoint
-	mux.HandleFunc("/", c.Handler())       // Root endpoint
+	mux.HandleFunc("/livez", probeHandler(c.Live))
+	mux.HandleFunc("/readyz", probeHandler(c.Ready))
+	mux.HandleFunc("/startupz", probeHandler(c.Started))
+	mux.HandleFunc("/status/resources", c.ResourcesHandler())
+	mux.HandleFunc("/metrics", c.MetricsHandler())
+	mux.HandleFunc("/", c.Handler()) // Root endpoint
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	c.mu.Lock()
+	for pattern, handler := range c.routes {
+		mux.Handle(pattern, handler)
+	}
+	c.server = server
+	c.mu.Unlock()
 
-	log.Printf("Starting health check server on %s", addr)
-	return http.ListenAndServe(addr, mux)
+	slog.Info("Starting health check server", "addr", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown gracefully stops the health check server, waiting for active requests until ctx expires
+func (c *Checker) Shutdown(ctx context.Context) error {
+	c.mu.RLock()
+	server := c.server
+	c.mu.RUnlock()
+
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
 }
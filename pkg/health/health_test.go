@@ -1,6 +1,7 @@
 package health
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -122,3 +123,158 @@ func TestHandlerUnhealthy(t *testing.T) {
 		t.Errorf("Expected unhealthy status in response, got %s", status.Status)
 	}
 }
+
+func TestShutdown(t *testing.T) {
+	checker := NewChecker()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- checker.StartServer("127.0.0.1:0")
+	}()
+
+	// Wait for the server to be registered before shutting it down
+	for i := 0; i < 100; i++ {
+		checker.mu.RLock()
+		started := checker.server != nil
+		checker.mu.RUnlock()
+		if started {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := checker.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("StartServer() returned %v after shutdown, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("StartServer() did not return after Shutdown()")
+	}
+}
+
+func TestProbeEndpoints(t *testing.T) {
+	checker := NewCheckerWithOptions(Options{
+		PollInterval:     time.Minute,
+		StaleAfterPolls:  3,
+		HeartbeatTimeout: time.Minute,
+	})
+
+	probe := func(check func() (bool, string)) int {
+		w := httptest.NewRecorder()
+		probeHandler(check)(w, httptest.NewRequest("GET", "/", nil))
+		return w.Code
+	}
+
+	// Still cloning: live, but not started or ready
+	if code := probe(checker.Live); code != http.StatusOK {
+		t.Errorf("livez before startup = %d, want 200", code)
+	}
+	if code := probe(checker.Started); code != http.StatusServiceUnavailable {
+		t.Errorf("startupz before startup = %d, want 503", code)
+	}
+	if code := probe(checker.Ready); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz before initial sync = %d, want 503", code)
+	}
+
+	checker.SetStarted()
+	checker.Heartbeat()
+
+	if code := probe(checker.Started); code != http.StatusOK {
+		t.Errorf("startupz after startup = %d, want 200", code)
+	}
+	if code := probe(checker.Ready); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz before initial sync = %d, want 503", code)
+	}
+
+	// A blocked or failed first run still makes the pod ready
+	checker.SetSyncBlocked("40 dashboards would be removed")
+	checker.SetLastAttempt(time.Now(), true)
+	if code := probe(checker.Ready); code != http.StatusOK {
+		t.Errorf("readyz after a blocked initial sync = %d, want 200", code)
+	}
+	if status := checker.GetStatus(); !status.Ready || !status.AttemptFailed || status.SyncBlocked == "" {
+		t.Errorf("GetStatus() = %+v, want ready with the blocked run reported", status)
+	}
+
+	// Sync loop stuck: heartbeat older than the watchdog timeout
+	checker.mu.Lock()
+	checker.lastHeartbeat = time.Now().Add(-2 * time.Minute)
+	checker.mu.Unlock()
+	if code := probe(checker.Live); code != http.StatusServiceUnavailable {
+		t.Errorf("livez with stale heartbeat = %d, want 503", code)
+	}
+}
+
+func TestStaleSync(t *testing.T) {
+	checker := NewCheckerWithOptions(Options{PollInterval: time.Minute, StaleAfterPolls: 3})
+	checker.SetGrafanaHealth(true)
+	checker.SetGitSyncHealth(true)
+
+	checker.SetLastSync(time.Now().Add(-2 * time.Minute))
+	if status := checker.GetStatus(); status.Status != "healthy" || status.Stale {
+		t.Errorf("Expected healthy status within threshold, got %s (stale=%v)", status.Status, status.Stale)
+	}
+
+	checker.SetLastSync(time.Now().Add(-4 * time.Minute))
+	if status := checker.GetStatus(); status.Status != "unhealthy" || !status.Stale {
+		t.Errorf("Expected unhealthy stale status, got %s (stale=%v)", status.Status, status.Stale)
+	}
+}
+
+func TestPausedStatus(t *testing.T) {
+	checker := NewChecker()
+
+	checker.SetPaused(true, time.Time{})
+	if status := checker.GetStatus(); !status.Paused {
+		t.Error("Expected paused status")
+	}
+
+	// An expired auto-resume time no longer counts as paused
+	checker.SetPaused(true, time.Now().Add(-time.Second))
+	if status := checker.GetStatus(); status.Paused {
+		t.Error("Expected expired pause to be reported as not paused")
+	}
+
+	checker.SetPaused(true, time.Time{})
+	checker.SetPinned("abc123")
+	if status := checker.GetStatus(); !status.Paused || status.PinnedCommit != "abc123" {
+		t.Errorf("Expected paused status pinned to abc123, got paused=%v pinned=%q", status.Paused, status.PinnedCommit)
+	}
+}
+
+func TestConfigError(t *testing.T) {
+	checker := NewChecker()
+
+	checker.SetConfigError("invalid config file: unknown setting \"pol_interval\"")
+	if status := checker.GetStatus(); status.ConfigError == "" {
+		t.Error("Expected config error to be reported")
+	}
+
+	checker.SetConfigError("")
+	if status := checker.GetStatus(); status.ConfigError != "" {
+		t.Errorf("Expected config error to be cleared, got %q", status.ConfigError)
+	}
+}
+
+func TestSyncBlocked(t *testing.T) {
+	checker := NewChecker()
+	checker.SetGrafanaHealth(true)
+	checker.SetGitSyncHealth(true)
+
+	checker.SetSyncBlocked("40 dashboards would be removed")
+	if status := checker.GetStatus(); status.Status != "degraded" || status.SyncBlocked == "" {
+		t.Errorf("Expected degraded status with the block reason, got %s (sync_blocked=%q)", status.Status, status.SyncBlocked)
+	}
+
+	checker.SetSyncBlocked("")
+	if status := checker.GetStatus(); status.Status != "healthy" {
+		t.Errorf("Expected healthy status once unblocked, got %s", status.Status)
+	}
+}
@@ -0,0 +1,67 @@
+package health
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// MetricsHandler returns an HTTP handler exposing health state in the Prometheus text format
+func (c *Checker) MetricsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := c.GetStatus()
+
+		resultCounts := map[string]int{ResultSynced: 0, ResultFailed: 0}
+		for _, res := range c.Resources("") {
+			resultCounts[res.Result]++
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		writeGauge(w, "grafana_git_sync_grafana_healthy", "Whether the Grafana API is reachable.", boolValue(status.GrafanaHealthy))
+		writeGauge(w, "grafana_git_sync_git_healthy", "Whether the last Git fetch succeeded.", boolValue(status.GitSyncHealthy))
+		writeGauge(w, "grafana_git_sync_ready", "Whether the first sync run has finished, successfully or not.", boolValue(status.Ready))
+		writeGauge(w, "grafana_git_sync_last_attempt_failed", "Whether the last sync run failed or was blocked.", boolValue(status.AttemptFailed))
+		writeGauge(w, "grafana_git_sync_last_attempt_timestamp_seconds", "Unix time at which the last sync run finished.", unixSeconds(status.LastAttempt))
+		writeGauge(w, "grafana_git_sync_stale", "Whether no sync has succeeded within the staleness threshold.", boolValue(status.Stale))
+		writeGauge(w, "grafana_git_sync_last_sync_timestamp_seconds", "Unix time of the last successful sync.", unixSeconds(status.LastSyncTime))
+		writeGauge(w, "grafana_git_sync_paused", "Whether the poll loop is paused.", boolValue(status.Paused))
+		writeGauge(w, "grafana_git_sync_paused_until_timestamp_seconds", "Unix time at which a paused loop resumes automatically, 0 if not scheduled.", unixSeconds(status.PausedUntil))
+		writeGauge(w, "grafana_git_sync_config_reload_failed", "Whether the last config reload was rejected.", boolValue(status.ConfigError != ""))
+		writeGauge(w, "grafana_git_sync_signature_rejected", "Whether the fetched commit was refused by signature verification.", boolValue(status.SignatureError != ""))
+		writeGauge(w, "grafana_git_sync_blocked", "Whether the last sync run was stopped by the safety thresholds.", boolValue(status.SyncBlocked != ""))
+
+		if status.GitCommit != "" {
+			fmt.Fprintln(w, "# HELP grafana_git_sync_git_revision_info Git ref and commit of the last fetch.")
+			fmt.Fprintln(w, "# TYPE grafana_git_sync_git_revision_info gauge")
+			fmt.Fprintf(w, "grafana_git_sync_git_revision_info{ref=%q,commit=%q} 1\n", status.GitRef, status.GitCommit)
+		}
+
+		fmt.Fprintln(w, "# HELP grafana_git_sync_resources Number of managed dashboard files by last sync result.")
+		fmt.Fprintln(w, "# TYPE grafana_git_sync_resources gauge")
+		for _, result := range []string{ResultSynced, ResultFailed} {
+			fmt.Fprintf(w, "grafana_git_sync_resources{result=%q} %d\n", result, resultCounts[result])
+		}
+	}
+}
+
+func writeGauge(w io.Writer, name, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	fmt.Fprintf(w, "%s %g\n", name, value)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.Unix())
+}
@@ -0,0 +1,44 @@
+package health
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	checker := NewChecker()
+	checker.SetGrafanaHealth(true)
+	checker.SetPaused(true, time.Time{})
+	checker.SetConfigError("bad config")
+	checker.SetSignatureError("commit 4b7b5a6 is not signed")
+	checker.SetSyncBlocked("40 dashboards would be removed")
+	checker.SetLastAttempt(time.Now(), true)
+	checker.SetRevision("tag:v2.1.0", "4b7b5a6ef3f8559bcf3c1d7da7648a3dfee523de")
+	checker.RecordResource(ResourceStatus{Path: "a.json", Result: ResultSynced})
+	checker.RecordResource(ResourceStatus{Path: "b.json", Result: ResultFailed})
+	checker.RecordResource(ResourceStatus{Path: "c.json", Result: ResultFailed})
+
+	w := httptest.NewRecorder()
+	checker.MetricsHandler()(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		"grafana_git_sync_grafana_healthy 1\n",
+		"grafana_git_sync_git_healthy 0\n",
+		"grafana_git_sync_paused 1\n",
+		"grafana_git_sync_config_reload_failed 1\n",
+		"grafana_git_sync_signature_rejected 1\n",
+		"grafana_git_sync_blocked 1\n",
+		"grafana_git_sync_ready 1\n",
+		"grafana_git_sync_last_attempt_failed 1\n",
+		`grafana_git_sync_git_revision_info{ref="tag:v2.1.0",commit="4b7b5a6ef3f8559bcf3c1d7da7648a3dfee523de"} 1` + "\n",
+		`grafana_git_sync_resources{result="synced"} 1` + "\n",
+		`grafana_git_sync_resources{result="failed"} 2` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Metrics output missing %q:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Resource sync results
+const (
+	ResultSynced = "synced"
+	ResultFailed = "failed"
+)
+
+// ResourceStatus is the last known sync outcome of a managed dashboard file
+type ResourceStatus struct {
+	Path        string    `json:"path"` // path in the Git repository
+	UID         string    `json:"uid,omitempty"`
+	Folder      string    `json:"folder,omitempty"`
+	Commit      string    `json:"commit,omitempty"` // last commit that synced successfully
+	LastAttempt time.Time `json:"last_attempt"`
+	Result      string    `json:"result"` // "synced" or "failed"
+	Error       string    `json:"error,omitempty"`
+}
+
+// RecordResource stores the outcome of a sync attempt for one file.
+// A failed attempt keeps the commit of the last successful sync.
+func (c *Checker) RecordResource(status ResourceStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.resources == nil {
+		c.resources = make(map[string]ResourceStatus)
+	}
+	if prev, ok := c.resources[status.Path]; ok && status.Result != ResultSynced {
+		status.Commit = prev.Commit
+		if status.UID == "" {
+			status.UID = prev.UID
+		}
+	}
+	c.resources[status.Path] = status
+}
+
+// RetainResources drops every recorded resource whose path is not in paths
+// and returns how many were dropped
+func (c *Checker) RetainResources(paths []string) int {
+	keep := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		keep[p] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dropped := 0
+	for path := range c.resources {
+		if !keep[path] {
+			delete(c.resources, path)
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// Resources returns the recorded resources sorted by path, optionally filtered by result
+func (c *Checker) Resources(result string) []ResourceStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	list := []ResourceStatus{}
+	for _, r := range c.resources {
+		if result == "" || r.Result == result {
+			list = append(list, r)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list
+}
+
+// ResourcesHandler returns an HTTP handler listing managed resources.
+// The optional "status" query parameter filters by result.
+func (c *Checker) ResourcesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resources := c.Resources(r.URL.Query().Get("status"))
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resources); err != nil {
+			slog.Error("Failed to encode resource status", "error", err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRecordResource(t *testing.T) {
+	checker := NewChecker()
+
+	checker.RecordResource(ResourceStatus{Path: "a.json", UID: "a", Commit: "c1", LastAttempt: time.Now(), Result: ResultSynced})
+	checker.RecordResource(ResourceStatus{Path: "a.json", Commit: "c2", LastAttempt: time.Now(), Result: ResultFailed, Error: "boom"})
+
+	resources := checker.Resources("")
+	if len(resources) != 1 {
+		t.Fatalf("Expected 1 resource, got %d", len(resources))
+	}
+	got := resources[0]
+	if got.Result != ResultFailed || got.Error != "boom" {
+		t.Errorf("Expected failed result with error, got %+v", got)
+	}
+	if got.Commit != "c1" {
+		t.Errorf("Expected failed attempt to keep last synced commit c1, got %s", got.Commit)
+	}
+	if got.UID != "a" {
+		t.Errorf("Expected UID to be kept from previous sync, got %s", got.UID)
+	}
+}
+
+func TestRetainResources(t *testing.T) {
+	checker := NewChecker()
+	checker.RecordResource(ResourceStatus{Path: "a.json", Result: ResultSynced})
+	checker.RecordResource(ResourceStatus{Path: "b.json", Result: ResultSynced})
+
+	if dropped := checker.RetainResources([]string{"b.json"}); dropped != 1 {
+		t.Errorf("RetainResources() = %d, want 1", dropped)
+	}
+
+	resources := checker.Resources("")
+	if len(resources) != 1 || resources[0].Path != "b.json" {
+		t.Errorf("Expected only b.json to remain, got %+v", resources)
+	}
+}
+
+func TestResourcesHandler(t *testing.T) {
+	checker := NewChecker()
+	checker.RecordResource(ResourceStatus{Path: "b.json", Result: ResultFailed, Error: "boom"})
+	checker.RecordResource(ResourceStatus{Path: "a.json", Result: ResultSynced})
+	checker.RecordResource(ResourceStatus{Path: "c.json", Result: ResultSynced})
+
+	w := httptest.NewRecorder()
+	checker.ResourcesHandler()(w, httptest.NewRequest("GET", "/status/resources", nil))
+
+	var all []ResourceStatus
+	if err := json.NewDecoder(w.Body).Decode(&all); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(all) != 3 || all[0].Path != "a.json" {
+		t.Errorf("Expected 3 resources sorted by path, got %+v", all)
+	}
+
+	w = httptest.NewRecorder()
+	checker.ResourcesHandler()(w, httptest.NewRequest("GET", "/status/resources?status=failed", nil))
+
+	var failed []ResourceStatus
+	if err := json.NewDecoder(w.Body).Decode(&failed); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(failed) != 1 || failed[0].Path != "b.json" {
+		t.Errorf("Expected only b.json with status=failed, got %+v", failed)
+	}
+}
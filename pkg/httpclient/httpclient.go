@@ -0,0 +1,113 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Options configures TLS and proxying for outgoing HTTP connections, shared by
+// the Grafana client and Git over HTTPS
+type Options struct {
+	// CAFile is a PEM bundle of certificate authorities trusted in addition to the system roots
+	CAFile string
+	// CertFile and KeyFile are the client certificate and key for mutual TLS
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify disables server certificate verification
+	InsecureSkipVerify bool
+
+	// HTTPProxy, HTTPSProxy and NoProxy override the HTTP_PROXY, HTTPS_PROXY
+	// and NO_PROXY environment variables (and their lowercase forms)
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+}
+
+// TLSConfig returns the TLS configuration for the options
+func (o Options) TLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", o.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return nil, errors.New("client certificate and key must be set together")
+	}
+	if o.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if o.InsecureSkipVerify {
+		slog.Warn("TLS certificate verification is disabled")
+		cfg.InsecureSkipVerify = true
+	}
+	return cfg, nil
+}
+
+// Proxy returns the proxy function for the options, falling back to the environment
+func (o Options) Proxy() func(*http.Request) (*url.URL, error) {
+	cfg := httpproxy.FromEnvironment()
+	if o.HTTPProxy != "" {
+		cfg.HTTPProxy = o.HTTPProxy
+	}
+	if o.HTTPSProxy != "" {
+		cfg.HTTPSProxy = o.HTTPSProxy
+	}
+	if o.NoProxy != "" {
+		cfg.NoProxy = o.NoProxy
+	}
+	proxy := cfg.ProxyFunc()
+	return func(r *http.Request) (*url.URL, error) {
+		return proxy(r.URL)
+	}
+}
+
+// Transport returns an HTTP transport with the TLS and proxy settings applied
+func (o Options) Transport() (*http.Transport, error) {
+	tlsConfig, err := o.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	for _, proxy := range []string{o.HTTPProxy, o.HTTPSProxy} {
+		if proxy == "" {
+			continue
+		}
+		// Like the environment variables, proxies may omit the http:// scheme
+		if !strings.Contains(proxy, "://") {
+			proxy = "http://" + proxy
+		}
+		if u, err := url.Parse(proxy); err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", proxy)
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = o.Proxy()
+	return transport, nil
+}
@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Log formats
+const (
+	FormatPretty = "pretty" // human-readable console output with emoji
+	FormatText   = "text"   // logfmt key=value pairs
+	FormatJSON   = "json"   // one JSON object per line
+)
+
+// level is shared by every handler so it can be changed while running
+var level = new(slog.LevelVar)
+
+// Setup installs the default slog logger, which the standard log package also
+// writes through, with the given format and minimum level
+func Setup(w io.Writer, format, lvl string) error {
+	if err := SetLevel(lvl); err != nil {
+		return err
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case FormatPretty, "":
+		handler = NewPrettyHandler(w, opts)
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("invalid log format %q (want pretty, text or json)", format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// SetLevel changes the minimum level of the installed logger
+func SetLevel(lvl string) error {
+	l, err := ParseLevel(lvl)
+	if err != nil {
+		return err
+	}
+	level.Set(l)
+	return nil
+}
+
+// ParseLevel parses debug, info, warn or error; empty means info
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log level %q (want debug, info, warn or error)", s)
+}
+
+// ValidFormat reports whether format is a supported log format
+func ValidFormat(format string) bool {
+	switch strings.ToLower(format) {
+	case FormatPretty, FormatText, FormatJSON, "":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetup(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "pretty", want: `⚠️ Upload failed commit=abc1234 path="teams/a b.json" grafana_status=412 duration=1.5s`},
+		{format: "text", want: `level=WARN msg="Upload failed" commit=abc1234 path="teams/a b.json" grafana_status=412 duration=1.5s`},
+		{format: "json", want: `"level":"WARN","msg":"Upload failed","commit":"abc1234","path":"teams/a b.json","grafana_status":412,"duration":1500000000}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Setup(&buf, tt.format, "info"); err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+			slog.Warn("Upload failed", "commit", "abc1234", "path", "teams/a b.json", "grafana_status", 412, "duration", 1500*time.Millisecond)
+			if got := buf.String(); !strings.Contains(got, tt.want) {
+				t.Errorf("output = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+
+	if err := Setup(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Error("Setup() should reject an unknown format")
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var buf bytes.Buffer
+	if err := Setup(&buf, "json", "warn"); err != nil {
+		t.Fatal(err)
+	}
+	slog.Info("hidden")
+	// The standard logger writes through slog at info level
+	log.Print("also hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("info records written at warn level: %s", buf.String())
+	}
+
+	if err := SetLevel("debug"); err != nil {
+		t.Fatal(err)
+	}
+	slog.Debug("shown")
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil || record["msg"] != "shown" {
+		t.Errorf("record = %v, %v", record, err)
+	}
+
+	if err := SetLevel("verbose"); err == nil {
+		t.Error("SetLevel() should reject an unknown level")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		level   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{level: "debug", want: slog.LevelDebug},
+		{level: "INFO", want: slog.LevelInfo},
+		{level: "", want: slog.LevelInfo},
+		{level: "warning", want: slog.LevelWarn},
+		{level: "error", want: slog.LevelError},
+		{level: "trace", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			got, err := ParseLevel(tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrettyHandler_Attrs(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewPrettyHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+
+	// Records without a time are written without a timestamp
+	r := slog.NewRecord(time.Time{}, slog.LevelError, "Request failed", 0)
+	r.Add("status", 500, "error", errors.New("bad gateway"))
+	if err := h.WithAttrs([]slog.Attr{slog.String("commit", "abc1234")}).WithGroup("grafana").Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	r = slog.NewRecord(time.Time{}, slog.LevelDebug, "Empty", 0)
+	r.Add("reason", "")
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "❌ Request failed commit=abc1234 grafana.status=500 grafana.error=\"bad gateway\"\n" +
+		"🔍 Empty reason=\"\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
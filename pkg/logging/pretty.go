@@ -0,0 +1,132 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// icons prefix pretty messages by level
+var icons = map[slog.Level]string{
+	slog.LevelDebug: "🔍",
+	slog.LevelInfo:  "ℹ️",
+	slog.LevelWarn:  "⚠️",
+	slog.LevelError: "❌",
+}
+
+// PrettyHandler writes console-friendly lines in the style of the standard
+// log package: "2006/01/02 15:04:05 ⚠️ message key=value"
+type PrettyHandler struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	opts   slog.HandlerOptions
+	attrs  string // preformatted attributes from WithAttrs
+	prefix string // group prefix for attribute keys
+}
+
+// NewPrettyHandler creates a PrettyHandler writing to w
+func NewPrettyHandler(w io.Writer, opts *slog.HandlerOptions) *PrettyHandler {
+	h := &PrettyHandler{mu: &sync.Mutex{}, w: w}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+// Enabled reports whether records at the level are written
+func (h *PrettyHandler) Enabled(_ context.Context, l slog.Level) bool {
+	min := slog.LevelInfo
+	if h.opts.Level != nil {
+		min = h.opts.Level.Level()
+	}
+	return l >= min
+}
+
+// Handle writes the record as a single line
+func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	if !r.Time.IsZero() {
+		b.WriteString(r.Time.Format("2006/01/02 15:04:05 "))
+	}
+	icon, ok := icons[r.Level]
+	if !ok {
+		icon = r.Level.String()
+	}
+	b.WriteString(icon)
+	b.WriteByte(' ')
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&b, h.prefix, a)
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+// WithAttrs returns a handler that adds attrs to every record
+func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	for _, a := range attrs {
+		writeAttr(&b, h.prefix, a)
+	}
+	h2 := *h
+	h2.attrs += b.String()
+	return &h2
+}
+
+// WithGroup returns a handler that qualifies attribute keys with name
+func (h *PrettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix += name + "."
+	return &h2
+}
+
+func writeAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			writeAttr(b, prefix, ga)
+		}
+		return
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(prefix)
+	b.WriteString(a.Key)
+	b.WriteByte('=')
+	var s string
+	switch a.Value.Kind() {
+	case slog.KindDuration:
+		s = a.Value.Duration().Round(time.Millisecond).String()
+	case slog.KindTime:
+		s = a.Value.Time().Format(time.RFC3339)
+	case slog.KindAny:
+		// Like slog.TextHandler, show struct field names
+		s = fmt.Sprintf("%+v", a.Value.Any())
+	default:
+		s = a.Value.String()
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+		s = strconv.Quote(s)
+	}
+	b.WriteString(s)
+}
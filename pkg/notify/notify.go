@@ -0,0 +1,294 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"grafana_git_sync/pkg/tracing"
+)
+
+var tracer = otel.Tracer("grafana_git_sync/pkg/notify")
+
+// Event kinds
+const (
+	KindSync      = "sync"      // summary of a sync run that processed a commit
+	KindAlert     = "alert"     // syncing failed for AlertAfter consecutive runs
+	KindRecovered = "recovered" // a sync succeeded after an alert
+)
+
+// Which events are sent
+const (
+	OnAll     = "all"     // summaries of every run that processed a commit, alerts and recoveries
+	OnFailure = "failure" // summaries of failed runs, alerts and recoveries
+	OnAlert   = "alert"   // alerts and recoveries only
+)
+
+// maxErrors limits the resource errors rendered by the chat targets
+const maxErrors = 10
+
+// ResourceError is a dashboard that failed to sync
+type ResourceError struct {
+	Path  string `json:"path"`
+	UID   string `json:"uid,omitempty"`
+	Error string `json:"error"`
+}
+
+// Event describes the outcome of a sync run
+type Event struct {
+	Kind      string    `json:"kind"`
+	Time      time.Time `json:"time"`
+	Commit    string    `json:"commit,omitempty"`
+	Ref       string    `json:"ref,omitempty"`
+	Message   string    `json:"message,omitempty"` // commit message
+	Author    string    `json:"author,omitempty"`
+	CommitURL string    `json:"commit_url,omitempty"`
+
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Failed  int `json:"failed"`
+	Deleted int `json:"deleted"` // dashboards deleted in Grafana, always 0: the sync never deletes dashboards
+	Removed int `json:"removed"` // files removed from the repository; their dashboards stay in Grafana
+
+	Errors              []ResourceError `json:"errors,omitempty"`
+	Error               string          `json:"error,omitempty"` // why the run failed
+	ConsecutiveFailures int             `json:"consecutive_failures"`
+	Duration            time.Duration   `json:"duration"`
+}
+
+// Failure reports whether the run failed
+func (e Event) Failure() bool {
+	return e.Error != ""
+}
+
+// ShortCommit returns the abbreviated commit hash
+func (e Event) ShortCommit() string {
+	if len(e.Commit) > 7 {
+		return e.Commit[:7]
+	}
+	return e.Commit
+}
+
+// Title is a one-line summary of the event
+func (e Event) Title() string {
+	switch {
+	case e.Kind == KindAlert:
+		return fmt.Sprintf("Dashboard sync failing for %d consecutive runs", e.ConsecutiveFailures)
+	case e.Kind == KindRecovered:
+		return "Dashboard sync recovered"
+	case e.Failure():
+		return "Dashboard sync failed"
+	default:
+		return "Dashboards synced"
+	}
+}
+
+// Counts summarises the resource counts
+func (e Event) Counts() string {
+	counts := fmt.Sprintf("%d created, %d updated, %d failed, %d deleted (the sync never deletes dashboards)", e.Created, e.Updated, e.Failed, e.Deleted)
+	if e.Removed > 0 {
+		counts += fmt.Sprintf(", %d removed from repository (not deleted in Grafana)", e.Removed)
+	}
+	return counts
+}
+
+// ErrorLines lists the failed resources, at most maxErrors of them, or the
+// run error when no resource failed
+func (e Event) ErrorLines() []string {
+	if len(e.Errors) == 0 {
+		if e.Error == "" {
+			return nil
+		}
+		return []string{e.Error}
+	}
+	var lines []string
+	for i, re := range e.Errors {
+		if i == maxErrors {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(e.Errors)-maxErrors))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", re.Path, re.Error))
+	}
+	return lines
+}
+
+// Notifier sends events to a target
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, e Event) error
+}
+
+// Options configures the notification targets and which events are sent
+type Options struct {
+	SlackURL        string
+	TeamsURL        string
+	WebhookURL      string
+	WebhookTemplate string // Go template of the webhook payload, empty for the event as JSON
+	On              string
+	AlertAfter      int // consecutive failed runs before an alert, 0 disables alerts
+}
+
+// Notifiers creates a Notifier for each configured target
+func (o Options) Notifiers(client *http.Client) ([]Notifier, error) {
+	var notifiers []Notifier
+	if o.SlackURL != "" {
+		notifiers = append(notifiers, NewSlack(o.SlackURL, client))
+	}
+	if o.TeamsURL != "" {
+		notifiers = append(notifiers, NewTeams(o.TeamsURL, client))
+	}
+	if o.WebhookURL != "" {
+		webhook, err := NewWebhook(o.WebhookURL, o.WebhookTemplate, client)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, webhook)
+	}
+	return notifiers, nil
+}
+
+// ValidOn reports whether on is a supported NOTIFY_ON value
+func ValidOn(on string) bool {
+	switch strings.ToLower(on) {
+	case OnAll, OnFailure, OnAlert, "":
+		return true
+	}
+	return false
+}
+
+// Dispatcher tracks consecutive failures and sends events to the notifiers
+type Dispatcher struct {
+	client *http.Client
+
+	mu         gosync.Mutex
+	opts       Options
+	notifiers  []Notifier
+	failures   int
+	alerted    bool
+	lastCommit string // commit and outcome of the last summary, to skip repeats
+	lastFailed bool
+}
+
+// NewDispatcher creates a Dispatcher sending through client
+func NewDispatcher(opts Options, client *http.Client) (*Dispatcher, error) {
+	d := &Dispatcher{client: client}
+	if err := d.Configure(opts); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Configure replaces the targets and options, keeping the failure count
+func (d *Dispatcher) Configure(opts Options) error {
+	notifiers, err := opts.Notifiers(d.client)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.opts = opts
+	d.notifiers = notifiers
+	return nil
+}
+
+// Options returns the current options
+func (d *Dispatcher) Options() Options {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.opts
+}
+
+// Record counts the outcome of a sync run and sends the resulting events.
+// processed is false for runs that found no new commit or failed before
+// reading it; they only count towards the failure streak. Repeated summaries
+// of the same commit and outcome, e.g. while failed uploads are retried, are
+// sent once.
+func (d *Dispatcher) Record(ctx context.Context, e Event, processed bool) {
+	d.mu.Lock()
+	var events []Event
+	if e.Failure() {
+		d.failures++
+		e.ConsecutiveFailures = d.failures
+	} else {
+		if d.alerted {
+			recovered := e
+			recovered.Kind = KindRecovered
+			recovered.ConsecutiveFailures = d.failures
+			events = append(events, recovered)
+		}
+		d.failures = 0
+		d.alerted = false
+	}
+
+	on := strings.ToLower(d.opts.On)
+	repeat := e.Commit == d.lastCommit && e.Failure() == d.lastFailed
+	if processed && !repeat && (on == OnAll || on == "" || on == OnFailure && e.Failure()) {
+		summary := e
+		summary.Kind = KindSync
+		events = append(events, summary)
+	}
+	if processed {
+		d.lastCommit, d.lastFailed = e.Commit, e.Failure()
+	}
+
+	if e.Failure() && d.opts.AlertAfter > 0 && d.failures == d.opts.AlertAfter {
+		alert := e
+		alert.Kind = KindAlert
+		events = append(events, alert)
+		d.alerted = true
+	}
+	notifiers := d.notifiers
+	d.mu.Unlock()
+
+	for _, event := range events {
+		d.send(ctx, notifiers, event)
+	}
+}
+
+// send delivers e to every notifier, logging failures
+func (d *Dispatcher) send(ctx context.Context, notifiers []Notifier, e Event) {
+	for _, n := range notifiers {
+		ctx, span := tracer.Start(ctx, "notify.send", trace.WithAttributes(
+			attribute.String("notify.target", n.Name()),
+			attribute.String("notify.kind", e.Kind),
+			tracing.Commit.String(e.Commit),
+		))
+		if err := n.Notify(ctx, e); err != nil {
+			tracing.Fail(span, err)
+			slog.Warn("Failed to send notification", "target", n.Name(), "kind", e.Kind, "commit", e.Commit, "error", err)
+		} else {
+			slog.Debug("Notification sent", "target", n.Name(), "kind", e.Kind, "commit", e.Commit)
+		}
+		span.End()
+	}
+}
+
+// postJSON sends payload to url and fails on non-2xx responses
+func postJSON(ctx context.Context, client *http.Client, url string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeNotifier struct {
+	kinds []string
+}
+
+func (f *fakeNotifier) Name() string { return "fake" }
+
+func (f *fakeNotifier) Notify(ctx context.Context, e Event) error {
+	f.kinds = append(f.kinds, e.Kind)
+	return nil
+}
+
+func TestDispatcher_Record(t *testing.T) {
+	ok := Event{Commit: "aaa", Created: 1}
+	failed := Event{Commit: "bbb", Failed: 1, Error: "upload failed"}
+
+	tests := []struct {
+		name      string
+		on        string
+		runs      []Event
+		processed bool
+		want      []string
+	}{
+		{
+			name:      "summary of each processed commit",
+			on:        OnAll,
+			runs:      []Event{ok, {Commit: "ccc", Updated: 2}},
+			processed: true,
+			want:      []string{KindSync, KindSync},
+		},
+		{
+			name:      "repeated failures of a commit are summarised once and alerted",
+			on:        OnAll,
+			runs:      []Event{failed, failed, failed, failed, ok},
+			processed: true,
+			want:      []string{KindSync, KindAlert, KindRecovered, KindSync},
+		},
+		{
+			name:      "failure summaries only",
+			on:        OnFailure,
+			runs:      []Event{ok, failed},
+			processed: true,
+			want:      []string{KindSync},
+		},
+		{
+			name:      "alerts only",
+			on:        OnAlert,
+			runs:      []Event{failed, failed, failed, ok},
+			processed: true,
+			want:      []string{KindAlert, KindRecovered},
+		},
+		{
+			name:      "unprocessed runs only count towards alerts",
+			on:        OnAll,
+			runs:      []Event{{Error: "fetch failed"}, {Error: "fetch failed"}, {Error: "fetch failed"}, {}},
+			processed: false,
+			want:      []string{KindAlert, KindRecovered},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeNotifier{}
+			d := &Dispatcher{opts: Options{On: tt.on, AlertAfter: 3}, notifiers: []Notifier{fake}}
+			for _, e := range tt.runs {
+				d.Record(context.Background(), e, tt.processed)
+			}
+			if strings.Join(fake.kinds, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("sent %v, want %v", fake.kinds, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvent_ErrorLines(t *testing.T) {
+	e := Event{Error: "2 uploads failed"}
+	if got := e.ErrorLines(); len(got) != 1 || got[0] != "2 uploads failed" {
+		t.Errorf("ErrorLines() = %v", got)
+	}
+	for i := 0; i < maxErrors+2; i++ {
+		e.Errors = append(e.Errors, ResourceError{Path: "a.json", Error: "boom"})
+	}
+	got := e.ErrorLines()
+	if len(got) != maxErrors+1 || got[0] != "a.json: boom" || got[maxErrors] != "... and 2 more" {
+		t.Errorf("ErrorLines() = %v", got)
+	}
+}
+
+// sink records the payloads POSTed to a local HTTP server
+func sink(t *testing.T, status int) (*httptest.Server, <-chan []byte) {
+	t.Helper()
+	received := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected %s request with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func TestNotifiers(t *testing.T) {
+	event := Event{
+		Kind:      KindSync,
+		Time:      time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC),
+		Commit:    "abc1234def",
+		Ref:       "branch:main",
+		Message:   "Add <latency> panel",
+		Author:    "Jane Doe",
+		CommitURL: "https://github.com/org/repo/commit/abc1234def",
+		Created:   1,
+		Updated:   2,
+		Failed:    1,
+		Removed:   1,
+		Errors:    []ResourceError{{Path: "teams/a.json", UID: "a", Error: "Grafana API error 412"}},
+		Error:     "1 dashboard failed",
+		Duration:  1500 * time.Millisecond,
+	}
+
+	tests := []struct {
+		name string
+		new  func(url string) (Notifier, error)
+		want []string
+	}{
+		{
+			name: "slack",
+			new:  func(url string) (Notifier, error) { return NewSlack(url, http.DefaultClient), nil },
+			want: []string{`:x: *Dashboard sync failed*`, `<https://github.com/org/repo/commit/abc1234def|abc1234>`, `Add &lt;latency&gt; panel`, `1 created, 2 updated, 1 failed, 0 deleted (the sync never deletes dashboards), 1 removed from repository (not deleted in Grafana) in 1.5s`, `teams/a.json: Grafana API error 412`},
+		},
+		{
+			name: "teams",
+			new:  func(url string) (Notifier, error) { return NewTeams(url, http.DefaultClient), nil },
+			want: []string{`"type":"AdaptiveCard"`, `"color":"Attention"`, `[abc1234](https://github.com/org/repo/commit/abc1234def) (branch:main)`, `1 created, 2 updated, 1 failed, 0 deleted (the sync never deletes dashboards), 1 removed from repository (not deleted in Grafana)`},
+		},
+		{
+			name: "webhook",
+			new:  func(url string) (Notifier, error) { return NewWebhook(url, "", http.DefaultClient) },
+			want: []string{`"kind":"sync"`, `"commit":"abc1234def"`, `"created":1`, `"deleted":0`, `"removed":1`, `"errors":[{"path":"teams/a.json","uid":"a","error":"Grafana API error 412"}]`},
+		},
+		{
+			name: "webhook template",
+			new: func(url string) (Notifier, error) {
+				return NewWebhook(url, `{"summary": {{json .Title}}, "commit": "{{.ShortCommit}}", "failed": {{.Failed}}}`, http.DefaultClient)
+			},
+			want: []string{`{"summary": "Dashboard sync failed", "commit": "abc1234", "failed": 1}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, received := sink(t, http.StatusOK)
+			n, err := tt.new(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := n.Notify(context.Background(), event); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			body := <-received
+			if !json.Valid(body) {
+				t.Errorf("payload is not valid JSON: %s", body)
+			}
+			// Compare against the decoded payload so escaping in the JSON does not matter
+			var decoded interface{}
+			json.Unmarshal(body, &decoded)
+			text := string(body)
+			if s, ok := decoded.(map[string]interface{})["text"].(string); ok {
+				text = s
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("payload does not contain %s:\n%s", want, text)
+				}
+			}
+		})
+	}
+}
+
+func TestNotify_Errors(t *testing.T) {
+	server, _ := sink(t, http.StatusNotFound)
+	if err := NewSlack(server.URL, http.DefaultClient).Notify(context.Background(), Event{}); err == nil {
+		t.Error("Notify() should fail on a 404 response")
+	}
+
+	if _, err := NewWebhook(server.URL, "{{.Title", http.DefaultClient); err == nil {
+		t.Error("NewWebhook() should reject an invalid template")
+	}
+	w, err := NewWebhook(server.URL, "{{.Missing}}", http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tmplErr error
+	if _, tmplErr = w.Render(Event{}); tmplErr == nil {
+		t.Error("Render() should fail on an unknown field")
+	}
+	if errors.Unwrap(tmplErr) == nil {
+		t.Errorf("Render() error = %v, want it to wrap the template error", tmplErr)
+	}
+}
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Slack posts events to a Slack incoming webhook
+type Slack struct {
+	url    string
+	client *http.Client
+}
+
+// NewSlack creates a Slack notifier for the incoming webhook url
+func NewSlack(url string, client *http.Client) *Slack {
+	return &Slack{url: url, client: client}
+}
+
+// Name identifies the target in logs
+func (s *Slack) Name() string {
+	return "slack"
+}
+
+// Notify posts e as a mrkdwn message
+func (s *Slack) Notify(ctx context.Context, e Event) error {
+	payload, err := json.Marshal(map[string]string{"text": slackText(e)})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.client, s.url, payload)
+}
+
+// slackText renders e in Slack mrkdwn
+func slackText(e Event) string {
+	icon := ":white_check_mark:"
+	if e.Kind == KindAlert || e.Failure() && e.Kind != KindRecovered {
+		icon = ":x:"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s *%s*", icon, slackEscape(e.Title()))
+	if e.Commit != "" {
+		commit := slackEscape(e.ShortCommit())
+		if e.CommitURL != "" {
+			commit = fmt.Sprintf("<%s|%s>", e.CommitURL, commit)
+		}
+		fmt.Fprintf(&b, "\nCommit %s", commit)
+		if e.Ref != "" {
+			fmt.Fprintf(&b, " (%s)", slackEscape(e.Ref))
+		}
+		if e.Message != "" {
+			fmt.Fprintf(&b, ": %s - %s", slackEscape(e.Message), slackEscape(e.Author))
+		}
+	}
+	if e.Kind == KindSync {
+		fmt.Fprintf(&b, "\n%s in %s", e.Counts(), e.Duration.Round(time.Millisecond))
+	}
+	if e.Kind != KindRecovered {
+		if lines := e.ErrorLines(); len(lines) > 0 {
+			fmt.Fprintf(&b, "\n```%s```", slackEscape(strings.Join(lines, "\n")))
+		}
+	}
+	return b.String()
+}
+
+// slackEscape escapes the control characters of Slack mrkdwn
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Teams posts events as Adaptive Cards to a Microsoft Teams workflow webhook
+type Teams struct {
+	url    string
+	client *http.Client
+}
+
+// NewTeams creates a Teams notifier for the workflow webhook url
+func NewTeams(url string, client *http.Client) *Teams {
+	return &Teams{url: url, client: client}
+}
+
+// Name identifies the target in logs
+func (t *Teams) Name() string {
+	return "teams"
+}
+
+// Notify posts e as an Adaptive Card message
+func (t *Teams) Notify(ctx context.Context, e Event) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     teamsCard(e),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, t.client, t.url, payload)
+}
+
+// teamsCard renders e as an Adaptive Card
+func teamsCard(e Event) map[string]interface{} {
+	color := "Good"
+	if e.Kind == KindAlert || e.Failure() && e.Kind != KindRecovered {
+		color = "Attention"
+	}
+	body := []map[string]interface{}{
+		{"type": "TextBlock", "text": e.Title(), "weight": "Bolder", "size": "Medium", "color": color, "wrap": true},
+	}
+
+	var facts []map[string]string
+	if e.Commit != "" {
+		commit := e.ShortCommit()
+		if e.CommitURL != "" {
+			commit = fmt.Sprintf("[%s](%s)", commit, e.CommitURL)
+		}
+		if e.Ref != "" {
+			commit += " (" + e.Ref + ")"
+		}
+		facts = append(facts, map[string]string{"title": "Commit", "value": commit})
+	}
+	if e.Message != "" {
+		facts = append(facts, map[string]string{"title": "Message", "value": e.Message})
+		facts = append(facts, map[string]string{"title": "Author", "value": e.Author})
+	}
+	if e.Kind == KindSync {
+		facts = append(facts, map[string]string{"title": "Dashboards", "value": e.Counts()})
+		facts = append(facts, map[string]string{"title": "Duration", "value": e.Duration.Round(time.Millisecond).String()})
+	}
+	if len(facts) > 0 {
+		body = append(body, map[string]interface{}{"type": "FactSet", "facts": facts})
+	}
+	if e.Kind != KindRecovered {
+		if lines := e.ErrorLines(); len(lines) > 0 {
+			body = append(body, map[string]interface{}{"type": "TextBlock", "text": strings.Join(lines, "\n\n"), "fontType": "Monospace", "wrap": true})
+		}
+	}
+
+	return map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+}
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+)
+
+// Webhook posts events as JSON to a generic webhook
+type Webhook struct {
+	url    string
+	tmpl   *template.Template // nil to send the event itself
+	client *http.Client
+}
+
+// NewWebhook creates a webhook notifier. tmpl is a Go template of the payload
+// rendered with the Event; its json function encodes a value as JSON, e.g.
+// {"text": {{json .Title}}, "commit": {{json .Commit}}}
+func NewWebhook(url, tmpl string, client *http.Client) (*Webhook, error) {
+	w := &Webhook{url: url, client: client}
+	if tmpl != "" {
+		t, err := template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Option("missingkey=error").Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook template: %w", err)
+		}
+		w.tmpl = t
+	}
+	return w, nil
+}
+
+// Name identifies the target in logs
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+// Notify posts e, rendered with the template if one is set
+func (w *Webhook) Notify(ctx context.Context, e Event) error {
+	payload, err := w.Render(e)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, w.client, w.url, payload)
+}
+
+// Render returns the payload for e
+func (w *Webhook) Render(e Event) ([]byte, error) {
+	if w.tmpl == nil {
+		return json.Marshal(e)
+	}
+	var buf bytes.Buffer
+	if err := w.tmpl.Execute(&buf, e); err != nil {
+		return nil, fmt.Errorf("failed to render webhook template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// toJSON encodes v for use inside a JSON template
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
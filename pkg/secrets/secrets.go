@@ -0,0 +1,174 @@
+package secrets
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider fetches secret values from a backend
+type Provider interface {
+	Fetch(ctx context.Context, ref string) ([]byte, error)
+}
+
+// FileProvider reads secrets from local files, such as Docker or Kubernetes secret mounts
+type FileProvider struct{}
+
+// Fetch reads the file at path
+func (FileProvider) Fetch(ctx context.Context, path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return data, nil
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{"file": FileProvider{}}
+)
+
+// Register makes a provider available for references of the form "<scheme>:<ref>"
+func Register(scheme string, p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[scheme] = p
+}
+
+// Resolve fetches the secret a reference points to. References look like
+// "<scheme>:<ref>"; references without a registered scheme are file paths.
+// Trailing newlines are removed since secret files usually end with one.
+func Resolve(ctx context.Context, ref string) (string, error) {
+	provider, target := lookup(ref)
+	data, err := provider.Fetch(ctx, target)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func lookup(ref string) (Provider, string) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if scheme, target, ok := strings.Cut(ref, ":"); ok {
+		if p, found := providers[scheme]; found {
+			if scheme == "file" {
+				target = strings.TrimPrefix(target, "//")
+			}
+			return p, target
+		}
+	}
+	return providers["file"], ref
+}
+
+type watch struct {
+	ref      string
+	onChange func(value string)
+	hash     [sha256.Size]byte
+}
+
+// Watcher polls secret references and reports changed values, so rotated
+// secrets are picked up without a restart
+type Watcher struct {
+	interval time.Duration
+	mu       sync.Mutex
+	watches  []*watch
+}
+
+// NewWatcher creates a watcher that checks its references every interval
+func NewWatcher(interval time.Duration) *Watcher {
+	return &Watcher{interval: interval}
+}
+
+// Watch calls onChange whenever the value behind ref changes. The current
+// value is read immediately as the baseline and is not reported.
+func (w *Watcher) Watch(ctx context.Context, ref string, onChange func(value string)) error {
+	value, err := Resolve(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.watches = append(w.watches, &watch{ref: ref, onChange: onChange, hash: sha256.Sum256([]byte(value))})
+	return nil
+}
+
+// Set replaces the watched references with refs, each reported to onChange.
+// References already watched keep their baseline, so a change is not missed.
+// References that cannot be read are skipped and returned as an error.
+func (w *Watcher) Set(ctx context.Context, refs []string, onChange func(value string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	current := make(map[string]*watch, len(w.watches))
+	for _, wt := range w.watches {
+		current[wt.ref] = wt
+	}
+	var watches []*watch
+	var errs []error
+	seen := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		if wt, ok := current[ref]; ok {
+			wt.onChange = onChange
+			watches = append(watches, wt)
+			continue
+		}
+		value, err := Resolve(ctx, ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ref, err))
+			continue
+		}
+		watches = append(watches, &watch{ref: ref, onChange: onChange, hash: sha256.Sum256([]byte(value))})
+	}
+	w.watches = watches
+	return errors.Join(errs...)
+}
+
+// Run checks all references every interval until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check(ctx)
+		}
+	}
+}
+
+// Check re-reads every reference once and reports those whose value changed
+func (w *Watcher) Check(ctx context.Context) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, wt := range w.watches {
+		value, err := Resolve(ctx, wt.ref)
+		if err != nil {
+			// Keep the previous value; the file may be mid-rotation
+			slog.Warn("Failed to refresh secret", "ref", wt.ref, "error", err)
+			continue
+		}
+		hash := sha256.Sum256([]byte(value))
+		if hash == wt.hash {
+			continue
+		}
+		wt.hash = hash
+		slog.Info("Secret changed, applying new value", "ref", wt.ref)
+		wt.onChange(value)
+	}
+}
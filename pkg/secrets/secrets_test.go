@@ -0,0 +1,123 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type staticProvider map[string]string
+
+func (p staticProvider) Fetch(ctx context.Context, ref string) ([]byte, error) {
+	v, ok := p[ref]
+	if !ok {
+		return nil, fmt.Errorf("secret %s not found", ref)
+	}
+	return []byte(v), nil
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	if err := os.WriteFile(path, []byte("s3cret\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	Register("test", staticProvider{"grafana/token": "from-provider"})
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain path", ref: path, want: "s3cret"},
+		{name: "file scheme", ref: "file://" + path, want: "s3cret"},
+		{name: "registered scheme", ref: "test:grafana/token", want: "from-provider"},
+		{name: "provider error", ref: "test:missing", wantErr: true},
+		{name: "missing file", ref: filepath.Join(dir, "missing"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(context.Background(), tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatcher_Check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("v1"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var changes []string
+	w := NewWatcher(0)
+	if err := w.Watch(context.Background(), path, func(v string) { changes = append(changes, v) }); err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	w.Check(context.Background())
+	if len(changes) != 0 {
+		t.Fatalf("unchanged secret reported: %v", changes)
+	}
+
+	if err := os.WriteFile(path, []byte("v2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w.Check(context.Background())
+	w.Check(context.Background())
+	if len(changes) != 1 || changes[0] != "v2" {
+		t.Fatalf("changes = %v, want [v2]", changes)
+	}
+
+	// A vanished file keeps the last value and reports nothing
+	os.Remove(path)
+	w.Check(context.Background())
+	if len(changes) != 1 {
+		t.Errorf("changes = %v, want no report for unreadable secret", changes)
+	}
+}
+
+func TestWatcher_Set(t *testing.T) {
+	dir := t.TempDir()
+	kept, added, dropped := filepath.Join(dir, "kept"), filepath.Join(dir, "added"), filepath.Join(dir, "dropped")
+	for _, path := range []string{kept, added, dropped} {
+		if err := os.WriteFile(path, []byte("v1"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var changes []string
+	onChange := func(v string) { changes = append(changes, v) }
+	w := NewWatcher(0)
+	if err := w.Set(context.Background(), []string{kept, dropped}, onChange); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	// Rotated before the references change: the kept baseline must still report it
+	if err := os.WriteFile(kept, []byte("v2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := w.Set(context.Background(), []string{kept, added, kept, filepath.Join(dir, "missing")}, onChange)
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Set() error = %v, want the unreadable reference", err)
+	}
+	for _, path := range []string{added, dropped} {
+		if err := os.WriteFile(path, []byte(filepath.Base(path)+"-v2"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Check(context.Background())
+	if strings.Join(changes, ",") != "v2,added-v2" {
+		t.Errorf("changes = %v, want [v2 added-v2]", changes)
+	}
+}
@@ -0,0 +1,87 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Collision kinds
+const (
+	CollisionUID   = "uid"
+	CollisionTitle = "title"
+)
+
+// Collision is a group of dashboard files that would overwrite each other in
+// Grafana: they share a UID, or a title within the same folder
+type Collision struct {
+	Kind   string   // CollisionUID or CollisionTitle
+	Value  string   // the shared UID or title
+	Folder string   // Grafana folder of a title collision, empty for the General folder
+	Paths  []string // repository paths, sorted
+}
+
+func (c Collision) String() string {
+	if c.Kind == CollisionTitle {
+		folder := c.Folder
+		if folder == "" {
+			folder = "General"
+		}
+		return fmt.Sprintf("title %q in folder %s is used by %s", c.Value, folder, strings.Join(c.Paths, ", "))
+	}
+	return fmt.Sprintf("UID %q is used by %s", c.Value, strings.Join(c.Paths, ", "))
+}
+
+// PathUID derives a stable dashboard UID from a repository path, so a dashboard
+// without a uid keeps the same one across syncs
+func PathUID(repoPath string) string {
+	sum := sha256.Sum256([]byte(repoPath))
+	return hex.EncodeToString(sum[:10])
+}
+
+// dashboardFields returns the uid and title of decoded dashboard content,
+// looking into the "dashboard" wrapper used by API exports
+func dashboardFields(content map[string]interface{}) (uid, title string) {
+	if inner, ok := content["dashboard"].(map[string]interface{}); ok {
+		content = inner
+	}
+	uid, _ = content["uid"].(string)
+	title, _ = content["title"].(string)
+	return uid, title
+}
+
+// findCollisions groups the files by UID and by folder and title, returning
+// every group with more than one file. Titles are compared case-insensitively,
+// as Grafana does, and a group is reported with the title of its first file
+func findCollisions(files []scannedFile) []Collision {
+	type key struct{ kind, folder, value string }
+	groups := make(map[key][]string)
+	values := make(map[key]string)
+	var order []key
+	add := func(k key, value, repoPath string) {
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+			values[k] = value
+		}
+		groups[k] = append(groups[k], repoPath)
+	}
+	for _, f := range files {
+		if f.uid != "" {
+			add(key{CollisionUID, "", f.uid}, f.uid, f.repoPath)
+		}
+		if f.title != "" {
+			add(key{CollisionTitle, f.folder, strings.ToLower(f.title)}, f.title, f.repoPath)
+		}
+	}
+
+	var collisions []Collision
+	for _, k := range order {
+		if paths := groups[k]; len(paths) > 1 {
+			sort.Strings(paths)
+			collisions = append(collisions, Collision{Kind: k.kind, Value: values[k], Folder: k.folder, Paths: paths})
+		}
+	}
+	return collisions
+}
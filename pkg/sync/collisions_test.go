@@ -0,0 +1,98 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadDashboards_Collisions(t *testing.T) {
+	repoDir := t.TempDir()
+	for name, content := range map[string]string{
+		"team-a/cpu.json":    `{"title": "CPU", "uid": "cpu"}`,
+		"team-b/cpu.json":    `{"title": "CPU", "uid": "cpu"}`,
+		"team-a/memory.json": `{"title": "MEMORY", "panels": []}`,
+		"team-a/mem.json":    `{"dashboard": {"title": "Memory", "uid": "mem"}}`,
+		"team-b/memory.json": `{"title": "Memory", "panels": []}`,
+		"disk.json":          `{"title": "Disk", "uid": "disk"}`,
+	} {
+		path := filepath.Join(repoDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	service := NewService("", t.TempDir())
+	files, err := service.ReadDashboards(newDirFS(repoDir))
+	if err != nil {
+		t.Fatalf("ReadDashboards() error = %v", err)
+	}
+
+	var synced []string
+	for _, f := range files {
+		synced = append(synced, service.RepoPath(f))
+	}
+	// Titles differing only in case collide, the same title in another folder is fine
+	if strings.Join(synced, ",") != "disk.json,team-b/memory.json" {
+		t.Errorf("ReadDashboards() synced %v, want disk.json and team-b/memory.json", synced)
+	}
+
+	want := []string{
+		`UID "cpu" is used by team-a/cpu.json, team-b/cpu.json`,
+		`title "Memory" in folder team-a is used by team-a/mem.json, team-a/memory.json`,
+	}
+	collisions := service.Collisions()
+	if len(collisions) != len(want) {
+		t.Fatalf("Collisions() = %+v, want %d", collisions, len(want))
+	}
+	for i, c := range collisions {
+		if c.String() != want[i] {
+			t.Errorf("Collisions()[%d] = %s, want %s", i, c, want[i])
+		}
+	}
+	if skipped := service.Skipped(); len(skipped) != 4 || !strings.HasPrefix(skipped[0].Reason, "collides: ") {
+		t.Errorf("Skipped() = %+v, want the four colliding files", skipped)
+	}
+}
+
+func TestLoadDashboard_GenerateUIDs(t *testing.T) {
+	repoDir := t.TempDir()
+	for name, content := range map[string]string{
+		"cpu.json":     `{"title": "CPU", "panels": []}`,
+		"wrapped.json": `{"dashboard": {"title": "Wrapped"}}`,
+		"disk.json":    `{"title": "Disk", "uid": "disk"}`,
+	} {
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	service := NewService("", t.TempDir())
+	service.SetGenerateUIDs(true)
+	files, err := service.ReadDashboards(newDirFS(repoDir))
+	if err != nil {
+		t.Fatalf("ReadDashboards() error = %v", err)
+	}
+
+	want := map[string]string{
+		"cpu.json":     PathUID("cpu.json"),
+		"wrapped.json": PathUID("wrapped.json"),
+		"disk.json":    "disk",
+	}
+	for _, f := range files {
+		dashboard, err := service.LoadDashboard(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := dashboard.UID(); got != want[service.RepoPath(f)] {
+			t.Errorf("UID of %s = %q, want %q", service.RepoPath(f), got, want[service.RepoPath(f)])
+		}
+	}
+	if PathUID("cpu.json") == PathUID("team/cpu.json") || len(PathUID("cpu.json")) > 40 {
+		t.Errorf("PathUID() = %q, want a distinct UID of at most 40 characters", PathUID("cpu.json"))
+	}
+}
@@ -0,0 +1,103 @@
+package sync
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// IgnoreFileName is the optional file at the repository root listing paths to
+// skip, one gitignore-style pattern per line
+const IgnoreFileName = ".grafanasyncignore"
+
+// Filter selects dashboard files by repository path using gitignore-style patterns
+type Filter struct {
+	include         gitignore.Matcher // nil selects every path
+	exclude         gitignore.Matcher // nil excludes nothing
+	excludePatterns []gitignore.Pattern
+}
+
+// NewFilter creates a filter. A path is selected when it matches one of the include
+// patterns (or include is empty) and is not matched by the exclude patterns.
+func NewFilter(include, exclude []string) *Filter {
+	f := &Filter{}
+	if ps := parsePatterns(include); len(ps) > 0 {
+		f.include = gitignore.NewMatcher(ps)
+	}
+	f.setExclude(parsePatterns(exclude))
+	return f
+}
+
+func (f *Filter) setExclude(ps []gitignore.Pattern) {
+	f.excludePatterns = ps
+	f.exclude = nil
+	if len(ps) > 0 {
+		f.exclude = gitignore.NewMatcher(ps)
+	}
+}
+
+// withIgnoreFile returns a copy of the filter that also excludes the patterns
+// listed in the ignore file at the root of fsys, if there is one
+func (f *Filter) withIgnoreFile(fsys fs.FS) *Filter {
+	data, err := fs.ReadFile(fsys, IgnoreFileName)
+	if err != nil {
+		return f
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	withFile := &Filter{include: f.include}
+	withFile.setExclude(append(append([]gitignore.Pattern{}, f.excludePatterns...), parsePatterns(lines)...))
+	return withFile
+}
+
+// excluded reports whether a slash-separated repository path is excluded
+func (f *Filter) excluded(repoPath string, isDir bool) bool {
+	return f.exclude != nil && f.exclude.Match(strings.Split(repoPath, "/"), isDir)
+}
+
+// included reports whether a slash-separated repository file path matches the include patterns
+func (f *Filter) included(repoPath string) bool {
+	return f.include == nil || f.include.Match(strings.Split(repoPath, "/"), false)
+}
+
+func parsePatterns(lines []string) []gitignore.Pattern {
+	var ps []gitignore.Pattern
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ps = append(ps, gitignore.ParsePattern(line, nil))
+	}
+	return ps
+}
+
+// IsDashboard reports whether decoded JSON content looks like a Grafana dashboard,
+// either bare or wrapped in a "dashboard" key as returned by the Grafana API
+func IsDashboard(content map[string]interface{}) bool {
+	if _, ok := content["dashboard"].(map[string]interface{}); ok {
+		return true
+	}
+	if _, ok := content["panels"].([]interface{}); ok {
+		return true
+	}
+	if _, ok := content["rows"].([]interface{}); ok {
+		return true
+	}
+	if _, ok := content["title"].(string); !ok {
+		return false
+	}
+	for _, key := range []string{"uid", "schemaVersion", "templating", "time"} {
+		if _, ok := content[key]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsDashboard(t *testing.T) {
+	tests := []struct {
+		name    string
+		content map[string]interface{}
+		want    bool
+	}{
+		{name: "API export wrapper", content: map[string]interface{}{"dashboard": map[string]interface{}{}}, want: true},
+		{name: "panels", content: map[string]interface{}{"panels": []interface{}{}}, want: true},
+		{name: "legacy rows", content: map[string]interface{}{"rows": []interface{}{}}, want: true},
+		{name: "title and uid", content: map[string]interface{}{"title": "CPU", "uid": "cpu"}, want: true},
+		{name: "package.json", content: map[string]interface{}{"name": "app", "version": "1.0.0"}, want: false},
+		{name: "title only", content: map[string]interface{}{"title": "Fixture"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsDashboard(tt.content); got != tt.want {
+				t.Errorf("IsDashboard() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		path     string
+		selected bool
+	}{
+		{name: "no patterns", path: "team/cpu.json", selected: true},
+		{name: "included", include: []string{"dashboards/**/*.json"}, path: "dashboards/team/cpu.json", selected: true},
+		{name: "not included", include: []string{"dashboards/"}, path: "tests/cpu.json", selected: false},
+		{name: "excluded by name", exclude: []string{"renovate.json"}, path: "renovate.json", selected: false},
+		{name: "excluded directory", exclude: []string{"fixtures/"}, path: "tests/fixtures/cpu.json", selected: false},
+		{name: "negated exclude", exclude: []string{"*.json", "!keep.json"}, path: "keep.json", selected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFilter(tt.include, tt.exclude)
+			if got := f.included(tt.path) && !f.excluded(tt.path, false); got != tt.selected {
+				t.Errorf("selected = %v, want %v", got, tt.selected)
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	repoDir := t.TempDir()
+	files := map[string]string{
+		"team/cpu.json":           `{"title": "CPU", "uid": "cpu", "panels": []}`,
+		"export.json":             `{"dashboard": {"title": "Export"}}`,
+		"package.json":            `{"name": "app"}`,
+		"broken.json":             `{`,
+		".vscode/settings.json":   `{"editor.tabSize": 2}`,
+		"tests/fixtures/one.json": `{"title": "Fixture", "uid": "fixture"}`,
+		IgnoreFileName:            "# editor settings\n.vscode/\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(repoDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := NewService("", t.TempDir())
+	svc.SetFilter(NewFilter(nil, []string{"tests/"}))
+
+	plan, err := svc.Plan(newDirFS(repoDir))
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+
+	if len(plan.Files) != 2 {
+		t.Fatalf("Plan() files = %+v, want 2", plan.Files)
+	}
+	if plan.Files[0].Path != "export.json" || plan.Files[1].Path != "team/cpu.json" || plan.Files[1].Folder != "team" {
+		t.Errorf("Plan() files = %+v", plan.Files)
+	}
+
+	// Excluded directories are pruned without being reported; other JSON files are
+	skipped := make(map[string]string)
+	for _, s := range plan.Skipped {
+		skipped[s.Path] = s.Reason
+	}
+	if len(skipped) != 2 || skipped["package.json"] != "not a dashboard" || skipped["broken.json"] == "" {
+		t.Errorf("Plan() skipped = %+v", plan.Skipped)
+	}
+}
@@ -0,0 +1,252 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	gosync "sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+
+	"grafana_git_sync/pkg/tracing"
+)
+
+var tracer = otel.Tracer("grafana_git_sync/pkg/sync")
+
+// Uploader is the subset of the Grafana client used to upload dashboards
+type Uploader interface {
+	GetFolderIDByPath(folderPath string) int
+	CreateFolderTree(ctx context.Context, folderPath string) (int, error)
+	UploadDashboardWithVersion(ctx context.Context, dashboard map[string]interface{}, folderID int, versionMessage string) (bool, error)
+}
+
+// PoolOptions configures concurrent dashboard uploads
+type PoolOptions struct {
+	Workers   int     // number of concurrent uploads, values below 1 mean 1
+	RateLimit float64 // maximum uploads per second across all workers, 0 disables the limit
+	// OnResult, if set, is called from the worker goroutines after each upload attempt
+	OnResult func(FileResult)
+}
+
+// FileResult is the outcome of processing a single dashboard file
+type FileResult struct {
+	FilePath   string
+	FolderPath string
+	UID        string
+	Created    bool // the dashboard did not exist in Grafana before
+	Err        error
+	Duration   time.Duration
+}
+
+// RunSummary collects the results of one upload run
+type RunSummary struct {
+	Total    int
+	Uploaded int
+	Created  int // uploaded dashboards that did not exist before
+	Failed   int
+	Results  []FileResult
+	Duration time.Duration
+}
+
+// Err returns all upload errors joined together, or nil if every file succeeded
+func (s *RunSummary) Err() error {
+	var errs []error
+	for _, r := range s.Results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.FilePath, r.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type uploadJob struct {
+	index     int
+	dashboard *Dashboard
+	folderID  int
+}
+
+// UploadDashboards loads and uploads the given dashboard files using a bounded worker pool.
+// Folders are resolved before any dashboard is uploaded, so dashboards never race their
+// parent folder creation. Cancelling ctx stops scheduling new uploads; files that were not
+// attempted are reported with the context error.
+func (s *Service) UploadDashboards(ctx context.Context, uploader Uploader, files []string, versionMessage string, opts PoolOptions) *RunSummary {
+	start := time.Now()
+	summary := &RunSummary{
+		Total:   len(files),
+		Results: make([]FileResult, len(files)),
+	}
+
+	// Load dashboards and resolve their folders sequentially
+	var jobs []uploadJob
+	folderIDs := make(map[string]int)
+	folderErrs := make(map[string]error)
+	for i, filePath := range files {
+		summary.Results[i].FilePath = filePath
+
+		dashboard, err := s.LoadDashboard(filePath)
+		if err != nil {
+			summary.Results[i].Err = fmt.Errorf("failed to load dashboard: %w", err)
+			continue
+		}
+		summary.Results[i].FolderPath = dashboard.FolderPath
+		summary.Results[i].UID = dashboard.UID()
+
+		folderID := 0
+		if dashboard.FolderPath != "" {
+			if err, failed := folderErrs[dashboard.FolderPath]; failed {
+				summary.Results[i].Err = err
+				continue
+			}
+			id, ok := folderIDs[dashboard.FolderPath]
+			if !ok {
+				id = uploader.GetFolderIDByPath(dashboard.FolderPath)
+				if id == 0 {
+					id, err = uploader.CreateFolderTree(ctx, dashboard.FolderPath)
+					if err != nil {
+						err = fmt.Errorf("failed to ensure folder %s: %w", dashboard.FolderPath, err)
+						folderErrs[dashboard.FolderPath] = err
+						summary.Results[i].Err = err
+						continue
+					}
+				}
+				folderIDs[dashboard.FolderPath] = id
+			}
+			folderID = id
+		}
+
+		jobs = append(jobs, uploadJob{index: i, dashboard: dashboard, folderID: folderID})
+	}
+
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	limiter := newRateLimiter(opts.RateLimit)
+	defer limiter.Stop()
+
+	queue := make(chan uploadJob)
+	var wg gosync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				result := &summary.Results[job.index]
+				if err := limiter.Wait(ctx); err != nil {
+					result.Err = err
+					continue
+				}
+				uploadCtx, span := tracer.Start(ctx, "sync.upload_dashboard", trace.WithAttributes(
+					tracing.Path.String(s.RepoPath(result.FilePath)),
+					tracing.DashboardUID.String(result.UID),
+					tracing.Folder.String(result.FolderPath),
+				))
+				uploadStart := time.Now()
+				result.Created, result.Err = uploader.UploadDashboardWithVersion(uploadCtx, job.dashboard.Content, job.folderID, versionMessage)
+				result.Duration = time.Since(uploadStart)
+				if result.Err != nil {
+					tracing.Fail(span, result.Err)
+				}
+				span.End()
+				if opts.OnResult != nil {
+					opts.OnResult(*result)
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for i, job := range jobs {
+		select {
+		case queue <- job:
+		case <-ctx.Done():
+			// Mark everything that was not handed to a worker
+			for _, skipped := range jobs[i:] {
+				summary.Results[skipped.index].Err = ctx.Err()
+			}
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	for _, r := range summary.Results {
+		if r.Err != nil {
+			summary.Failed++
+		} else {
+			summary.Uploaded++
+			if r.Created {
+				summary.Created++
+			}
+		}
+	}
+	summary.Duration = time.Since(start)
+
+	return summary
+}
+
+// rateLimiter spaces calls evenly to at most a given number per second
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+// ValidateRate checks that perSecond, 0 for unlimited, can be enforced:
+// finite, not negative and at most one upload per nanosecond
+func ValidateRate(perSecond float64) error {
+	if perSecond == 0 {
+		return nil
+	}
+	if math.IsNaN(perSecond) || perSecond < 0 || rateInterval(perSecond) == 0 {
+		return fmt.Errorf("rate must be between 0 and %g per second, got %v", float64(time.Second), perSecond)
+	}
+	return nil
+}
+
+// rateInterval returns the spacing between calls at perSecond, or 0 if it
+// does not fit a ticker interval
+func rateInterval(perSecond float64) time.Duration {
+	interval := float64(time.Second) / perSecond
+	if math.IsNaN(interval) || interval < 1 || interval > math.MaxInt64 {
+		return 0
+	}
+	return time.Duration(interval)
+}
+
+// newRateLimiter spaces calls to perSecond; rates ValidateRate rejects are unlimited
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return &rateLimiter{}
+	}
+	interval := rateInterval(perSecond)
+	if interval == 0 {
+		return &rateLimiter{}
+	}
+	return &rateLimiter{ticker: time.NewTicker(interval)}
+}
+
+// Wait blocks until the next call is allowed or ctx is cancelled
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l.ticker == nil {
+		return ctx.Err()
+	}
+	select {
+	case <-l.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop releases the limiter's resources
+func (l *rateLimiter) Stop() {
+	if l.ticker != nil {
+		l.ticker.Stop()
+	}
+}
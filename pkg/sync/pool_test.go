@@ -0,0 +1,209 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	gosync "sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"grafana_git_sync/pkg/tracing"
+)
+
+type fakeUploader struct {
+	mu        gosync.Mutex
+	folders   map[string]int
+	uploads   map[string]int // dashboard title -> folder ID
+	failTitle string
+}
+
+func newFakeUploader() *fakeUploader {
+	return &fakeUploader{folders: make(map[string]int), uploads: make(map[string]int)}
+}
+
+func (f *fakeUploader) GetFolderIDByPath(folderPath string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.folders[folderPath]
+}
+
+func (f *fakeUploader) CreateFolderTree(ctx context.Context, folderPath string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := len(f.folders) + 1
+	f.folders[folderPath] = id
+	return id, nil
+}
+
+func (f *fakeUploader) UploadDashboardWithVersion(ctx context.Context, dashboard map[string]interface{}, folderID int, versionMessage string) (bool, error) {
+	title, _ := dashboard["title"].(string)
+	if title == f.failTitle {
+		return false, errors.New("boom")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, exists := f.uploads[title]
+	f.uploads[title] = folderID
+	return !exists, nil
+}
+
+func writeDashboards(t *testing.T, dir string, count int) []string {
+	t.Helper()
+	var files []string
+	for i := 0; i < count; i++ {
+		folder := filepath.Join(dir, fmt.Sprintf("folder%d", i%3))
+		if err := os.MkdirAll(folder, 0755); err != nil {
+			t.Fatalf("Failed to create folder: %v", err)
+		}
+		path := filepath.Join(folder, fmt.Sprintf("dash%d.json", i))
+		content := fmt.Sprintf(`{"title": "dash%d"}`, i)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write dashboard: %v", err)
+		}
+		files = append(files, path)
+	}
+	return files
+}
+
+func TestUploadDashboards(t *testing.T) {
+	dir := t.TempDir()
+	files := writeDashboards(t, dir, 20)
+	service := NewService("", dir)
+	uploader := newFakeUploader()
+
+	summary := service.UploadDashboards(context.Background(), uploader, files, "", PoolOptions{Workers: 4})
+
+	if summary.Total != 20 || summary.Uploaded != 20 || summary.Failed != 0 {
+		t.Fatalf("unexpected summary: total=%d uploaded=%d failed=%d", summary.Total, summary.Uploaded, summary.Failed)
+	}
+	if err := summary.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+	if summary.Created != 20 {
+		t.Errorf("created=%d, want 20", summary.Created)
+	}
+	if again := service.UploadDashboards(context.Background(), uploader, files[:5], "", PoolOptions{}); again.Created != 0 || again.Uploaded != 5 {
+		t.Errorf("re-upload: created=%d uploaded=%d, want 0 and 5", again.Created, again.Uploaded)
+	}
+	if len(uploader.folders) != 3 {
+		t.Errorf("expected 3 folders to be created, got %d", len(uploader.folders))
+	}
+	for title, folderID := range uploader.uploads {
+		if folderID == 0 {
+			t.Errorf("dashboard %s uploaded without a folder", title)
+		}
+	}
+}
+
+func TestValidateRate(t *testing.T) {
+	tests := []struct {
+		rate    float64
+		wantErr bool
+	}{
+		{0, false},
+		{10, false},
+		{0.001, false},
+		{1e9, false},
+		{2e9, true},
+		{math.Inf(1), true},
+		{math.NaN(), true},
+		{-1, true},
+		{1e-12, true}, // interval overflows a time.Duration
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.rate), func(t *testing.T) {
+			if err := ValidateRate(tt.rate); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRate(%v) error = %v, wantErr %v", tt.rate, err, tt.wantErr)
+			}
+			// Rates that cannot be enforced must not panic in NewTicker
+			newRateLimiter(tt.rate).Stop()
+		})
+	}
+}
+
+func TestUploadDashboards_AggregatesErrors(t *testing.T) {
+	dir := t.TempDir()
+	files := writeDashboards(t, dir, 5)
+	files = append(files, filepath.Join(dir, "missing.json"))
+	service := NewService("", dir)
+	uploader := newFakeUploader()
+	uploader.failTitle = "dash2"
+
+	summary := service.UploadDashboards(context.Background(), uploader, files, "", PoolOptions{Workers: 2, RateLimit: 1000})
+
+	if summary.Uploaded != 4 || summary.Failed != 2 {
+		t.Errorf("uploaded=%d failed=%d, want 4 and 2", summary.Uploaded, summary.Failed)
+	}
+	if summary.Err() == nil {
+		t.Error("Err() should report failed uploads")
+	}
+}
+
+func TestUploadDashboards_Cancelled(t *testing.T) {
+	dir := t.TempDir()
+	files := writeDashboards(t, dir, 5)
+	service := NewService("", dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	summary := service.UploadDashboards(ctx, newFakeUploader(), files, "", PoolOptions{Workers: 2})
+
+	if summary.Uploaded != 0 || summary.Failed != 5 {
+		t.Errorf("uploaded=%d failed=%d, want 0 and 5", summary.Uploaded, summary.Failed)
+	}
+	if !errors.Is(summary.Err(), context.Canceled) {
+		t.Errorf("Err() = %v, want context.Canceled", summary.Err())
+	}
+}
+
+func TestUploadDashboards_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	dir := t.TempDir()
+	files := writeDashboards(t, dir, 3)
+	service := NewService("", dir)
+	uploader := newFakeUploader()
+	uploader.failTitle = "dash1"
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "sync.run")
+	service.UploadDashboards(ctx, uploader, files, "", PoolOptions{Workers: 2})
+	root.End()
+
+	failed := 0
+	paths := make(map[string]bool)
+	for _, span := range recorder.Ended() {
+		if span.Name() != "sync.upload_dashboard" {
+			continue
+		}
+		if span.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("span %s is not a child of the run span", span.Name())
+		}
+		for _, attr := range span.Attributes() {
+			if attr.Key == tracing.Path {
+				paths[attr.Value.AsString()] = true
+			}
+		}
+		if span.Status().Code == codes.Error {
+			failed++
+		}
+	}
+	if len(paths) != 3 || !paths["folder1/dash1.json"] {
+		t.Errorf("upload span paths = %v, want the 3 dashboards", paths)
+	}
+	if failed != 1 {
+		t.Errorf("%d failed upload spans, want 1", failed)
+	}
+}
@@ -0,0 +1,79 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrBlocked is wrapped by the errors of runs stopped by the safety thresholds
+var ErrBlocked = errors.New("sync blocked by safety thresholds")
+
+// Thresholds stop a run that would change too many dashboards at once, e.g.
+// after a bad merge or a misconfigured subdirectory. They compare the commit
+// with the dashboard files of the last applied sync.
+type Thresholds struct {
+	MaxDeletions     int     // files removed from the repository, 0 disables the check
+	MaxChangePercent float64 // changed and removed files as a percentage of the previous ones, 0 disables the check
+}
+
+// Check returns why a run is blocked, or nil if it may proceed. previous is the
+// number of files of the last applied sync, current the number in the commit,
+// changed how many of those differ from the last sync and removed how many of
+// the previous files are missing from the commit.
+func (t Thresholds) Check(previous, current, changed, removed int) error {
+	if t == (Thresholds{}) {
+		return nil
+	}
+	if previous > 0 && current == 0 {
+		return fmt.Errorf("%w: the commit has no dashboards, %d were synced before", ErrBlocked, previous)
+	}
+	if t.MaxDeletions > 0 && removed > t.MaxDeletions {
+		return fmt.Errorf("%w: %d dashboards would be removed, more than the limit of %d", ErrBlocked, removed, t.MaxDeletions)
+	}
+	if t.MaxChangePercent > 0 && previous > 0 {
+		percent := 100 * float64(changed+removed) / float64(previous)
+		if percent > t.MaxChangePercent {
+			return fmt.Errorf("%w: %d of %d dashboards would change or be removed (%.0f%%), more than the limit of %g%%", ErrBlocked, changed+removed, previous, percent, t.MaxChangePercent)
+		}
+	}
+	return nil
+}
+
+// State is what the last applied sync left in Grafana, kept across restarts so
+// the first run after a start can be checked against it
+type State struct {
+	Commit string            `json:"commit"`
+	Hashes map[string]string `json:"hashes"` // file hashes, see Service.Hashes
+}
+
+// LoadState reads a state saved by SaveState; a missing file is an empty state
+func LoadState(path string) (State, error) {
+	var state State
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("invalid sync state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// SaveState writes the state to path
+func SaveState(path string, state State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	// Replace atomically so a crash never leaves a truncated state
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
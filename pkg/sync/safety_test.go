@@ -0,0 +1,68 @@
+package sync
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestThresholds_Check(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds Thresholds
+		previous   int
+		current    int
+		changed    int
+		removed    int
+		wantErr    bool
+	}{
+		{name: "disabled", thresholds: Thresholds{}, previous: 500, removed: 500},
+		{name: "deletions at the limit", thresholds: Thresholds{MaxDeletions: 10}, previous: 100, current: 90, removed: 10},
+		{name: "too many deletions", thresholds: Thresholds{MaxDeletions: 10}, previous: 100, current: 89, removed: 11, wantErr: true},
+		{name: "changes within the limit", thresholds: Thresholds{MaxChangePercent: 50}, previous: 100, current: 100, changed: 50},
+		{name: "too many changes", thresholds: Thresholds{MaxChangePercent: 50}, previous: 100, current: 100, changed: 51, wantErr: true},
+		{name: "deletions count as changes", thresholds: Thresholds{MaxChangePercent: 25}, previous: 100, current: 70, changed: 5, removed: 30, wantErr: true},
+		{name: "most files removed", thresholds: Thresholds{MaxChangePercent: 50}, previous: 100, current: 10, changed: 1, removed: 90, wantErr: true},
+		{name: "no dashboards left", thresholds: Thresholds{MaxDeletions: 1000}, previous: 100, removed: 100, wantErr: true},
+		{name: "first sync", thresholds: Thresholds{MaxChangePercent: 25}, current: 100, changed: 100},
+		{name: "empty repository", thresholds: Thresholds{MaxChangePercent: 25}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.thresholds.Check(tt.previous, tt.current, tt.changed, tt.removed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrBlocked) {
+				t.Errorf("Check() error = %v, want it to wrap ErrBlocked", err)
+			}
+		})
+	}
+}
+
+func TestState_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	state, err := LoadState(path)
+	if err != nil || state.Commit != "" || state.Hashes != nil {
+		t.Fatalf("LoadState() of a missing file = %+v, %v, want an empty state", state, err)
+	}
+
+	want := State{Commit: "abc1234", Hashes: map[string]string{"cpu.json": "0123"}}
+	if err := SaveState(path, want); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+	state, err = LoadState(path)
+	if err != nil || state.Commit != want.Commit || state.Hashes["cpu.json"] != "0123" {
+		t.Errorf("LoadState() = %+v, %v, want %+v", state, err, want)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadState(path); err == nil {
+		t.Error("LoadState() of a corrupt file should fail")
+	}
+}
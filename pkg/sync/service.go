@@ -6,30 +6,67 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/fs"
-	"log"
+	"log/slog"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
 
 // Service handles dashboard synchronization
+//
+// Dashboards are identified by their path under the dashboards directory, which
+// mirrors the Grafana folder layout. The directory is only written to when the
+// mirror is enabled; otherwise dashboard content is kept in memory.
 type Service struct {
-	repoDir       string
-	repoSubdir    string
+	sources       []Source
 	dashboardsDir string
+	mirror        bool
+	generateUIDs  bool
 	fileHashes    map[string]string // Track file hashes to detect changes
+	filter        *Filter
+	skipped       []SkippedFile
+	contents      map[string][]byte // dashboards dir path -> content, from the last read
+	repoPaths     map[string]string // dashboards dir path -> repository path, from the last read
+	mirrored      map[string]bool   // files written to the mirror by the last read
+	collisions    []Collision       // from the last read
 }
 
-// NewService creates a new sync service
-func NewService(repoDir, repoSubdir, dashboardsDir string) *Service {
+// NewService creates a new sync service for a single repository subdirectory
+func NewService(repoSubdir, dashboardsDir string) *Service {
+	dir, err := cleanRepoDir(repoSubdir)
+	if err != nil {
+		// Keep the directory so the error is reported by every sync
+		dir = repoSubdir
+	}
+	return NewServiceWithSources([]Source{{Dir: dir}}, dashboardsDir)
+}
+
+// NewServiceWithSources creates a sync service for several repository directories,
+// as returned by ParseSources
+func NewServiceWithSources(sources []Source, dashboardsDir string) *Service {
 	return &Service{
-		repoDir:       repoDir,
-		repoSubdir:    repoSubdir,
+		sources:       sources,
 		dashboardsDir: dashboardsDir,
 		fileHashes:    make(map[string]string),
+		filter:        NewFilter(nil, nil),
+		contents:      make(map[string][]byte),
+		repoPaths:     make(map[string]string),
+		mirrored:      make(map[string]bool),
 	}
 }
 
+// SetMirror enables writing dashboards to the dashboards directory, e.g. for Grafana file provisioning
+func (s *Service) SetMirror(enabled bool) {
+	s.mirror = enabled
+}
+
+// SetGenerateUIDs gives dashboards without a uid one derived from their repository path
+func (s *Service) SetGenerateUIDs(enabled bool) {
+	s.generateUIDs = enabled
+}
+
 // Dashboard represents a dashboard file with its metadata
 type Dashboard struct {
 	FilePath   string
@@ -37,68 +74,292 @@ type Dashboard struct {
 	Content    map[string]interface{}
 }
 
-// CopyDashboards copies all JSON dashboard files from the repo to the dashboards directory
-func (s *Service) CopyDashboards() ([]string, error) {
-	log.Println("📂 Updating dashboards...")
-	var updatedFiles []string
+// UID returns the dashboard UID, looking into the "dashboard" wrapper used by API exports
+func (d *Dashboard) UID() string {
+	uid, _ := dashboardFields(d.Content)
+	return uid
+}
+
+// SkippedFile is a JSON file in the repository that is not synced
+type SkippedFile struct {
+	Path   string // path in the Git repository
+	Reason string
+}
+
+// PlannedFile is a dashboard file that would be synced
+type PlannedFile struct {
+	Path   string // path in the Git repository
+	Folder string // Grafana folder path, empty for the General folder
+}
+
+// Plan lists the files a sync would upload and the ones it would skip
+type Plan struct {
+	Files   []PlannedFile
+	Skipped []SkippedFile
+}
+
+// SetFilter replaces the include/exclude patterns used to select dashboard files
+func (s *Service) SetFilter(f *Filter) {
+	s.filter = f
+}
+
+// Skipped returns the files skipped by the last ReadDashboards call
+func (s *Service) Skipped() []SkippedFile {
+	return s.skipped
+}
+
+// Collisions returns the colliding dashboards found by the last ReadDashboards
+// call. Their files are skipped.
+func (s *Service) Collisions() []Collision {
+	return s.collisions
+}
+
+// scannedFile is a selected dashboard file
+type scannedFile struct {
+	repoPath string // slash-separated path in the repository
+	destRel  string // path relative to the dashboards directory
+	content  []byte
+	uid      string // as uploaded, possibly generated
+	title    string
+	folder   string // Grafana folder path
+}
 
-	err := filepath.Walk(s.repoDir, func(path string, info fs.FileInfo, err error) error {
+// scan walks the configured repository directories in fsys and selects dashboard
+// files using the filter, the ignore file and the file content. Files that
+// collide with each other in Grafana are skipped and returned as collisions.
+func (s *Service) scan(fsys fs.FS) ([]scannedFile, []SkippedFile, []Collision, error) {
+	filter := s.filter.withIgnoreFile(fsys)
+
+	var files []scannedFile
+	var skipped []SkippedFile
+	skip := func(repoPath, reason string) {
+		slog.Debug("Skipping file", "path", repoPath, "reason", reason)
+		skipped = append(skipped, SkippedFile{Path: repoPath, Reason: reason})
+	}
+	claimed := make(map[string]string) // destRel -> repo path that claimed it
+
+	for _, src := range s.sources {
+		dir, err := cleanRepoDir(src.Dir)
 		if err != nil {
-			return err
+			return nil, nil, nil, err
 		}
-		if info.IsDir() || filepath.Ext(path) != ".json" {
-			return nil
+		root := dir
+		if root == "" {
+			root = "."
 		}
-
-		srcDir := s.repoDir
-		if s.repoSubdir != "." && s.repoSubdir != "" {
-			srcDir = filepath.Join(s.repoDir, s.repoSubdir)
-		}
-
-		relPath, err := filepath.Rel(srcDir, path)
+		info, err := fs.Stat(fsys, root)
 		if err != nil {
-			return err
+			return nil, nil, nil, fmt.Errorf("repository subdirectory %q not found: %w", dir, err)
+		}
+		if !info.IsDir() {
+			return nil, nil, nil, fmt.Errorf("repository subdirectory %q is not a directory", dir)
 		}
-		destPath := filepath.Join(s.dashboardsDir, relPath)
 
-		content, err := os.ReadFile(path)
-		if err != nil {
-			log.Printf("❌ Failed to read file %s: %v", path, err)
+		err = fs.WalkDir(fsys, root, func(repoPath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			rel := repoPath
+			if root != "." {
+				rel = strings.TrimPrefix(strings.TrimPrefix(repoPath, root), "/")
+			}
+
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return fs.SkipDir
+				}
+				if repoPath != root && filter.excluded(repoPath, true) {
+					slog.Debug("Skipping directory excluded by pattern", "path", repoPath)
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if path.Ext(repoPath) != ".json" {
+				return nil
+			}
+
+			if !filter.included(repoPath) {
+				skip(repoPath, "not matched by include patterns")
+				return nil
+			}
+			if filter.excluded(repoPath, false) {
+				skip(repoPath, "excluded by pattern")
+				return nil
+			}
+
+			name := repoPath
+			if d.Type()&fs.ModeSymlink != 0 {
+				target, err := resolveLink(fsys, repoPath)
+				if err != nil {
+					skip(repoPath, err.Error())
+					return nil
+				}
+				name = target
+			} else if !d.Type().IsRegular() {
+				return nil
+			}
+
+			content, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				slog.Warn("Failed to read file", "path", repoPath, "error", err)
+				skip(repoPath, fmt.Sprintf("unreadable: %v", err))
+				return nil
+			}
+
+			var doc interface{}
+			if err := json.Unmarshal(content, &doc); err != nil {
+				slog.Warn("Invalid JSON", "path", repoPath, "error", err)
+				skip(repoPath, fmt.Sprintf("invalid JSON: %v", err))
+				return nil
+			}
+			obj, ok := doc.(map[string]interface{})
+			if !ok || !IsDashboard(obj) {
+				skip(repoPath, "not a dashboard")
+				return nil
+			}
+
+			destRel := filepath.Join(filepath.FromSlash(src.Folder), filepath.FromSlash(rel))
+			if other, ok := claimed[destRel]; ok {
+				skip(repoPath, fmt.Sprintf("maps to the same Grafana path as %s", other))
+				return nil
+			}
+			claimed[destRel] = repoPath
+			uid, title := dashboardFields(obj)
+			if uid == "" && s.generateUIDs {
+				uid = PathUID(repoPath)
+			}
+			files = append(files, scannedFile{
+				repoPath: repoPath,
+				destRel:  destRel,
+				content:  content,
+				uid:      uid,
+				title:    title,
+				folder:   s.detectFolderFromPath(filepath.Join(s.dashboardsDir, destRel)),
+			})
 			return nil
+		})
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error walking repo: %w", err)
 		}
+	}
 
-		// Validate JSON
-		var tmp interface{}
-		if err := json.Unmarshal(content, &tmp); err != nil {
-			log.Printf("❌ Invalid JSON in %s: %v", path, err)
-			return nil
+	// Colliding files would overwrite each other on every sync, so none of them is uploaded
+	collisions := findCollisions(files)
+	if len(collisions) == 0 {
+		return files, skipped, nil, nil
+	}
+	reasons := make(map[string][]string)
+	for _, c := range collisions {
+		for _, p := range c.Paths {
+			reasons[p] = append(reasons[p], c.String())
+		}
+	}
+	kept := files[:0]
+	for _, f := range files {
+		if r, ok := reasons[f.repoPath]; ok {
+			skip(f.repoPath, "collides: "+strings.Join(r, "; "))
+			continue
 		}
+		kept = append(kept, f)
+	}
+	return kept, skipped, collisions, nil
+}
+
+// Plan reports which files in fsys a sync would upload and which it would skip
+func (s *Service) Plan(fsys fs.FS) (*Plan, error) {
+	files, skipped, _, err := s.scan(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Skipped: skipped}
+	for _, f := range files {
+		plan.Files = append(plan.Files, PlannedFile{
+			Path:   f.repoPath,
+			Folder: f.folder,
+		})
+	}
+	return plan, nil
+}
+
+// ReadDashboards selects the dashboard files in fsys, normally the tree of the
+// synced commit, and keeps their content in memory. It returns their paths under
+// the dashboards directory, which identify them in the other methods. The
+// mirror is only updated by WriteMirror.
+func (s *Service) ReadDashboards(fsys fs.FS) ([]string, error) {
+	slog.Debug("Reading dashboards")
+
+	files, skipped, collisions, err := s.scan(fsys)
+	if err != nil {
+		return nil, err
+	}
+	s.skipped = skipped
+	s.collisions = collisions
+	if len(skipped) > 0 {
+		slog.Info("Skipped JSON files that are not dashboards or are filtered out", "count", len(skipped))
+	}
+	for _, c := range collisions {
+		slog.Warn("Dashboard collision, not uploading the colliding files", "kind", c.Kind, "value", c.Value, "folder", c.Folder, "paths", strings.Join(c.Paths, ","))
+	}
+
+	paths := make([]string, 0, len(files))
+	s.contents = make(map[string][]byte, len(files))
+	s.repoPaths = make(map[string]string, len(files))
+	for _, f := range files {
+		destPath := filepath.Join(s.dashboardsDir, f.destRel)
+		s.contents[destPath] = f.content
+		s.repoPaths[destPath] = f.repoPath
+		paths = append(paths, destPath)
+	}
+	return paths, nil
+}
 
+// WriteMirror writes the dashboards read last to the dashboards directory and
+// removes files it wrote earlier that are no longer in the repository. It does
+// nothing unless the mirror is enabled.
+func (s *Service) WriteMirror() {
+	if !s.mirror {
+		return
+	}
+	mirrored := make(map[string]bool, len(s.contents))
+	for destPath := range s.contents {
 		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
-			log.Printf("❌ Failed to create directory for %s: %v", destPath, err)
-			return nil
+			slog.Error("Failed to create mirror directory", "path", destPath, "error", err)
+			continue
 		}
 
-		if err := os.WriteFile(destPath, content, 0644); err != nil {
-			log.Printf("❌ Failed to write file %s: %v", destPath, err)
-			return nil
+		if err := os.WriteFile(destPath, s.contents[destPath], 0644); err != nil {
+			slog.Error("Failed to write mirrored dashboard", "path", destPath, "error", err)
+			continue
 		}
 
-		log.Printf("✅ Dashboard updated: %s", destPath)
-		updatedFiles = append(updatedFiles, destPath)
-		return nil
-	})
+		slog.Debug("Dashboard mirrored", "path", destPath)
+		mirrored[destPath] = true
+	}
 
-	if err != nil {
-		return nil, fmt.Errorf("error walking repo: %w", err)
+	for destPath := range s.mirrored {
+		if !mirrored[destPath] {
+			if err := os.Remove(destPath); err != nil && !os.IsNotExist(err) {
+				slog.Warn("Failed to remove mirrored dashboard", "path", destPath, "error", err)
+			}
+		}
 	}
+	s.mirrored = mirrored
+}
 
-	return updatedFiles, nil
+// readFile returns the content of a dashboard read by ReadDashboards, falling back
+// to the file system for paths it did not read
+func (s *Service) readFile(filePath string) ([]byte, error) {
+	if content, ok := s.contents[filePath]; ok {
+		return content, nil
+	}
+	return os.ReadFile(filePath)
 }
 
 // LoadDashboard reads and parses a dashboard file
 func (s *Service) LoadDashboard(filePath string) (*Dashboard, error) {
-	content, err := os.ReadFile(filePath)
+	content, err := s.readFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read dashboard file: %w", err)
 	}
@@ -108,6 +369,16 @@ func (s *Service) LoadDashboard(filePath string) (*Dashboard, error) {
 		return nil, fmt.Errorf("invalid JSON in file: %w", err)
 	}
 
+	if s.generateUIDs {
+		inner := dashboard
+		if wrapped, ok := dashboard["dashboard"].(map[string]interface{}); ok {
+			inner = wrapped
+		}
+		if uid, _ := inner["uid"].(string); uid == "" {
+			inner["uid"] = PathUID(s.RepoPath(filePath))
+		}
+	}
+
 	folderPath := s.detectFolderFromPath(filePath)
 
 	return &Dashboard{
@@ -133,6 +404,28 @@ func (s *Service) GetUniqueFolders(dashboardFiles []string) []string {
 	return folders
 }
 
+// RepoPath maps a file in the dashboards directory back to its path in the repository
+func (s *Service) RepoPath(filePath string) string {
+	if repoPath, ok := s.repoPaths[filePath]; ok {
+		return repoPath
+	}
+
+	rel, err := filepath.Rel(s.dashboardsDir, filePath)
+	if err != nil {
+		return filePath
+	}
+	rel = filepath.ToSlash(rel)
+	for _, src := range s.sources {
+		if src.Folder == "" {
+			return path.Join(src.Dir, rel)
+		}
+		if inner, ok := strings.CutPrefix(rel, src.Folder+"/"); ok {
+			return path.Join(src.Dir, inner)
+		}
+	}
+	return rel
+}
+
 func (s *Service) detectFolderFromPath(filePath string) string {
 	rel, err := filepath.Rel(s.dashboardsDir, filepath.Dir(filePath))
 	if err != nil {
@@ -230,14 +523,64 @@ func (s *Service) HasFileChanged(path string, content []byte) bool {
 	return false
 }
 
-// GetChangedFiles returns list of files that changed since last sync
+// RecordHash records the current hash of a file, so it is not treated as changed
+// until its content changes again
+func (s *Service) RecordHash(filePath string) error {
+	content, err := s.readFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read dashboard: %w", err)
+	}
+	s.fileHashes[filePath] = computeFileHash(content)
+	return nil
+}
+
+// ForgetFile drops the recorded hash of a file so it is treated as changed next time
+func (s *Service) ForgetFile(path string) {
+	delete(s.fileHashes, path)
+}
+
+// Hashes returns a copy of the recorded hashes, to be restored with SetHashes
+func (s *Service) Hashes() map[string]string {
+	return maps.Clone(s.fileHashes)
+}
+
+// SetHashes replaces the recorded hashes, e.g. to undo GetChangedFiles for a run
+// that applied nothing
+func (s *Service) SetHashes(hashes map[string]string) {
+	s.fileHashes = maps.Clone(hashes)
+}
+
+// CountChanges compares the files with hashes, a map returned by Hashes, without
+// recording anything. It returns how many files differ and how many hashed
+// files are no longer among them.
+func (s *Service) CountChanges(allFiles []string, hashes map[string]string) (changed, removed int) {
+	present := make(map[string]bool, len(allFiles))
+	for _, filePath := range allFiles {
+		present[filePath] = true
+		content, err := s.readFile(filePath)
+		if err != nil || hashes[filePath] != computeFileHash(content) {
+			changed++
+		}
+	}
+	for filePath := range hashes {
+		if !present[filePath] {
+			removed++
+		}
+	}
+	return changed, removed
+}
+
+// GetChangedFiles returns list of files that changed since last sync and
+// forgets the hashes of files that are no longer present
 func (s *Service) GetChangedFiles(allFiles []string) ([]string, error) {
 	changed := []string{}
+	present := make(map[string]bool, len(allFiles))
 	
 	for _, filePath := range allFiles {
-		content, err := os.ReadFile(filePath)
+		present[filePath] = true
+		content, err := s.readFile(filePath)
 		if err != nil {
-			log.Printf("⚠️ Failed to read %s: %v", filePath, err)
+			slog.Warn("Failed to read dashboard", "path", filePath, "error", err)
 			continue
 		}
 		
@@ -245,6 +588,7 @@ func (s *Service) GetChangedFiles(allFiles []string) ([]string, error) {
 			changed = append(changed, filePath)
 		}
 	}
+	maps.DeleteFunc(s.fileHashes, func(filePath, _ string) bool { return !present[filePath] })
 	
 	return changed, nil
 }
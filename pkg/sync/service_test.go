@@ -7,7 +7,7 @@ import (
 )
 
 func TestNewService(t *testing.T) {
-	service := NewService("/tmp/repo", "subdir", "/tmp/dashboards")
+	service := NewService("subdir", "/tmp/dashboards")
 	if service == nil {
 		t.Error("NewService() returned nil")
 	}
@@ -15,7 +15,7 @@ func TestNewService(t *testing.T) {
 
 func TestLoadDashboard(t *testing.T) {
 	tmpDir := t.TempDir()
-	service := NewService(tmpDir, "", tmpDir)
+	service := NewService("", tmpDir)
 
 	// Create a valid dashboard file
 	validDash := `{
@@ -57,7 +57,7 @@ func TestLoadDashboard(t *testing.T) {
 	}
 }
 
-func TestCopyDashboards(t *testing.T) {
+func TestReadDashboards_Mirror(t *testing.T) {
 	srcDir := t.TempDir()
 	dstDir := t.TempDir()
 
@@ -80,15 +80,17 @@ func TestCopyDashboards(t *testing.T) {
 		}
 	}
 
-	service := NewService(srcDir, "", dstDir)
-	files, err := service.CopyDashboards()
+	service := NewService("", dstDir)
+	service.SetMirror(true)
+	files, err := service.ReadDashboards(newDirFS(srcDir))
 	if err != nil {
-		t.Fatalf("CopyDashboards() error = %v", err)
+		t.Fatalf("ReadDashboards() error = %v", err)
 	}
 
 	if len(files) == 0 {
-		t.Error("CopyDashboards() returned no files")
+		t.Error("ReadDashboards() returned no files")
 	}
+	service.WriteMirror()
 
 	// Verify destination structure
 	for _, folder := range folders {
@@ -104,7 +106,7 @@ func TestCopyDashboards(t *testing.T) {
 	}
 }
 
-func TestCopyDashboards_WithSubdir(t *testing.T) {
+func TestReadDashboards_WithSubdir(t *testing.T) {
 	srcDir := t.TempDir()
 	dstDir := t.TempDir()
 	subdir := "dashboards"
@@ -121,13 +123,264 @@ func TestCopyDashboards_WithSubdir(t *testing.T) {
 		t.Fatalf("Failed to create dashboard: %v", err)
 	}
 
-	service := NewService(srcDir, subdir, dstDir)
-	files, err := service.CopyDashboards()
+	service := NewService(subdir, dstDir)
+	files, err := service.ReadDashboards(newDirFS(srcDir))
 	if err != nil {
-		t.Fatalf("CopyDashboards() error = %v", err)
+		t.Fatalf("ReadDashboards() error = %v", err)
 	}
 
 	if len(files) == 0 {
-		t.Error("CopyDashboards() returned no files")
+		t.Error("ReadDashboards() returned no files")
+	}
+
+	// Without the mirror nothing is written, but the content is still available
+	if _, err := os.Stat(files[0]); !os.IsNotExist(err) {
+		t.Errorf("ReadDashboards() wrote %s without the mirror enabled", files[0])
+	}
+	if _, err := service.LoadDashboard(files[0]); err != nil {
+		t.Errorf("LoadDashboard() error = %v", err)
+	}
+}
+
+func TestRepoPath(t *testing.T) {
+	service := NewService("dashboards", "/tmp/dashboards")
+
+	got := service.RepoPath("/tmp/dashboards/team/cpu.json")
+	if got != "dashboards/team/cpu.json" {
+		t.Errorf("RepoPath() = %s, want dashboards/team/cpu.json", got)
+	}
+}
+
+func TestDashboardUID(t *testing.T) {
+	tests := []struct {
+		name    string
+		content map[string]interface{}
+		want    string
+	}{
+		{"top level", map[string]interface{}{"uid": "abc"}, "abc"},
+		{"api export wrapper", map[string]interface{}{"dashboard": map[string]interface{}{"uid": "def"}}, "def"},
+		{"missing", map[string]interface{}{"title": "No UID"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Dashboard{Content: tt.content}
+			if got := d.UID(); got != tt.want {
+				t.Errorf("UID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadDashboards_Scoping(t *testing.T) {
+	repoDir := t.TempDir()
+	outside := t.TempDir()
+	// Same title in different folders, without a uid to collide on
+	dash := `{"title": "CPU", "schemaVersion": 39}`
+	for _, name := range []string{
+		"teams/a/cpu.json",
+		"teams/a/.git/config.json",
+		"teams/b/cpu.json",
+		"other/cpu.json",
+	} {
+		path := filepath.Join(repoDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(dash), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.json"), []byte(dash), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.json"), filepath.Join(repoDir, "teams/a/escape.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	dstDir := t.TempDir()
+	sources, err := ParseSources("teams/a=Team A,teams/b=Team B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewServiceWithSources(sources, dstDir)
+
+	files, err := service.ReadDashboards(newDirFS(repoDir))
+	if err != nil {
+		t.Fatalf("ReadDashboards() error = %v", err)
+	}
+
+	want := map[string]string{
+		filepath.Join(dstDir, "Team A", "cpu.json"): "teams/a/cpu.json",
+		filepath.Join(dstDir, "Team B", "cpu.json"): "teams/b/cpu.json",
+	}
+	if len(files) != len(want) {
+		t.Fatalf("ReadDashboards() = %v, want %d files", files, len(want))
+	}
+	for _, f := range files {
+		if repoPath, ok := want[f]; !ok || service.RepoPath(f) != repoPath {
+			t.Errorf("unexpected file %s (repo path %s)", f, service.RepoPath(f))
+		}
+	}
+
+	skipped := service.Skipped()
+	if len(skipped) != 1 || skipped[0].Path != "teams/a/escape.json" {
+		t.Errorf("Skipped() = %+v, want the escaping symlink", skipped)
+	}
+}
+
+func TestReadDashboards_MissingSubdir(t *testing.T) {
+	service := NewService("missing", t.TempDir())
+	if _, err := service.ReadDashboards(newDirFS(t.TempDir())); err == nil {
+		t.Error("ReadDashboards() with a missing subdirectory should fail")
+	}
+}
+
+func TestGetChangedFiles_SetHashes(t *testing.T) {
+	repoDir := t.TempDir()
+	write := func(content string) {
+		if err := os.WriteFile(filepath.Join(repoDir, "a.json"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	service := NewService("", t.TempDir())
+	changed := func() int {
+		files, err := service.ReadDashboards(newDirFS(repoDir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		changedFiles, _ := service.GetChangedFiles(files)
+		return len(changedFiles)
+	}
+
+	write(`{"dashboard": {"title": "v1"}}`)
+	if got := changed(); got != 1 {
+		t.Fatalf("first read changed %d files, want 1", got)
+	}
+	hashes := service.Hashes()
+	write(`{"dashboard": {"title": "v2"}}`)
+	if got := changed(); got != 1 {
+		t.Fatalf("edit changed %d files, want 1", got)
+	}
+
+	// Undo the recorded edit, as for a blocked run, then revert the file
+	service.SetHashes(hashes)
+	write(`{"dashboard": {"title": "v1"}}`)
+	if got := changed(); got != 0 {
+		t.Errorf("revert changed %d files, want 0", got)
+	}
+}
+
+func TestGetChangedFiles_ForgetsRemovedFiles(t *testing.T) {
+	repoDir := t.TempDir()
+	for _, name := range []string{"a.json", "b.json"} {
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(`{"dashboard": {}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	service := NewService("", t.TempDir())
+	files, err := service.ReadDashboards(newDirFS(repoDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetChangedFiles(files); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetChangedFiles(files[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if hashes := service.Hashes(); len(hashes) != 1 || hashes[files[0]] == "" {
+		t.Errorf("Hashes() = %v, want only %s", hashes, files[0])
+	}
+}
+
+func TestCountChanges(t *testing.T) {
+	repoDir := t.TempDir()
+	for _, name := range []string{"a.json", "b.json"} {
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(`{"dashboard": {"title": "`+name+`"}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	service := NewService("", t.TempDir())
+	files, err := service.ReadDashboards(newDirFS(repoDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed, removed := service.CountChanges(files, nil); changed != 2 || removed != 0 {
+		t.Errorf("CountChanges() without hashes = %d, %d, want 2, 0", changed, removed)
+	}
+
+	if err := service.RecordHash(files[0]); err != nil {
+		t.Fatal(err)
+	}
+	hashes := service.Hashes()
+	hashes["gone.json"] = "0123"
+	service.SetHashes(nil)
+	if changed, removed := service.CountChanges(files, hashes); changed != 1 || removed != 1 {
+		t.Errorf("CountChanges() = %d, %d, want 1, 1", changed, removed)
+	}
+	if len(service.Hashes()) != 0 {
+		t.Error("CountChanges() should not record hashes")
+	}
+}
+
+func TestRecordHash(t *testing.T) {
+	repoDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repoDir, "a.json"), []byte(`{"dashboard": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	service := NewService("", t.TempDir())
+	files, err := service.ReadDashboards(newDirFS(repoDir))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("ReadDashboards() = %v, %v", files, err)
+	}
+
+	if err := service.RecordHash(files[0]); err != nil {
+		t.Fatalf("RecordHash() error = %v", err)
+	}
+	if changed, _ := service.GetChangedFiles(files); len(changed) != 0 {
+		t.Errorf("GetChangedFiles() = %v after RecordHash, want none", changed)
+	}
+	if err := service.RecordHash(filepath.Join(repoDir, "missing.json")); err == nil {
+		t.Error("RecordHash() should fail for an unreadable file")
+	}
+}
+
+func TestReadDashboards_MirrorRemovesDeleted(t *testing.T) {
+	repoDir := t.TempDir()
+	dstDir := t.TempDir()
+	for _, name := range []string{"a.json", "b.json"} {
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(`{"dashboard": {}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unrelated := filepath.Join(dstDir, "provisioned-elsewhere.json")
+	if err := os.WriteFile(unrelated, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewService("", dstDir)
+	service.SetMirror(true)
+	if _, err := service.ReadDashboards(newDirFS(repoDir)); err != nil {
+		t.Fatal(err)
+	}
+	service.WriteMirror()
+	if err := os.Remove(filepath.Join(repoDir, "b.json")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.ReadDashboards(newDirFS(repoDir)); err != nil {
+		t.Fatal(err)
+	}
+	// Reading alone leaves the mirror untouched, e.g. while a run is blocked
+	if _, err := os.Stat(filepath.Join(dstDir, "b.json")); err != nil {
+		t.Error("Expected the mirror to be kept until WriteMirror")
+	}
+	service.WriteMirror()
+
+	if _, err := os.Stat(filepath.Join(dstDir, "b.json")); !os.IsNotExist(err) {
+		t.Error("Expected deleted dashboard to be removed from the mirror")
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Error("Expected files not written by the mirror to be kept")
 	}
 }
@@ -0,0 +1,101 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Source is a repository directory whose dashboards are synced, optionally
+// placed under a Grafana root folder
+type Source struct {
+	Dir    string // slash-separated path relative to the repository root, "" for the root
+	Folder string // Grafana folder the directory maps to, "" to keep its layout at the top level
+}
+
+// ParseSources parses a comma-separated list of repository directories, each
+// optionally mapped to a Grafana root folder with "=", e.g.
+// "teams/a=Team A,teams/b=Team B,shared". An empty spec selects the repository root.
+func ParseSources(spec string) ([]Source, error) {
+	var sources []Source
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		dir, folder, _ := strings.Cut(entry, "=")
+
+		clean, err := cleanRepoDir(strings.TrimSpace(dir))
+		if err != nil {
+			return nil, err
+		}
+		folder = strings.Trim(strings.TrimSpace(folder), "/")
+		if strings.Contains(folder, "..") {
+			return nil, fmt.Errorf("invalid folder %q for %s", folder, dir)
+		}
+		sources = append(sources, Source{Dir: clean, Folder: folder})
+	}
+
+	if len(sources) == 0 {
+		return []Source{{}}, nil
+	}
+	return sources, nil
+}
+
+// cleanRepoDir normalizes a repository directory and rejects paths that leave the repository
+func cleanRepoDir(dir string) (string, error) {
+	dir = filepath.ToSlash(dir)
+	if path.IsAbs(dir) || filepath.IsAbs(dir) {
+		return "", fmt.Errorf("repository subdirectory %q must be relative", dir)
+	}
+	clean := path.Clean(dir)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("repository subdirectory %q is outside the repository", dir)
+	}
+	if clean == "." {
+		return "", nil
+	}
+	return clean, nil
+}
+
+// ReadLinkFS is implemented by file systems that can report symlink targets
+type ReadLinkFS interface {
+	fs.FS
+	ReadLink(name string) (string, error)
+}
+
+// maxLinkHops limits how many symlinks are followed for one file
+const maxLinkHops = 8
+
+var errLinkEscapes = errors.New("symlink points outside the repository")
+
+// resolveLink follows the symlink name within fsys and returns the path it resolves
+// to, rejecting targets outside fsys
+func resolveLink(fsys fs.FS, name string) (string, error) {
+	rl, ok := fsys.(ReadLinkFS)
+	if !ok {
+		return "", errors.New("symlinks are not supported")
+	}
+
+	for hops := 0; hops < maxLinkHops; hops++ {
+		target, err := rl.ReadLink(name)
+		if err != nil {
+			if hops == 0 {
+				return "", fmt.Errorf("unreadable symlink: %w", err)
+			}
+			return name, nil // no longer a symlink
+		}
+		target = filepath.ToSlash(target)
+		if path.IsAbs(target) || filepath.IsAbs(target) {
+			return "", errLinkEscapes
+		}
+		name = path.Join(path.Dir(name), target)
+		if name == ".." || strings.HasPrefix(name, "../") {
+			return "", errLinkEscapes
+		}
+	}
+	return "", errors.New("too many levels of symlinks")
+}
@@ -0,0 +1,87 @@
+package sync
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []Source
+		wantErr bool
+	}{
+		{name: "empty selects root", spec: "", want: []Source{{}}},
+		{name: "single", spec: "dashboards/", want: []Source{{Dir: "dashboards"}}},
+		{name: "dot", spec: ".", want: []Source{{}}},
+		{
+			name: "mapped folders",
+			spec: "teams/a=Team A, teams/b = Team B/Prod ,shared",
+			want: []Source{{Dir: "teams/a", Folder: "Team A"}, {Dir: "teams/b", Folder: "Team B/Prod"}, {Dir: "shared"}},
+		},
+		{name: "traversal", spec: "../other", wantErr: true},
+		{name: "hidden traversal", spec: "a/../../other", wantErr: true},
+		{name: "absolute", spec: "/etc", wantErr: true},
+		{name: "folder traversal", spec: "a=../x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSources(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSources() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newDirFS returns a local directory as a stand-in for a commit tree. Unlike
+// os.DirFS it reports symlink targets and refuses to open paths that resolve
+// outside dir.
+func newDirFS(dir string) fs.FS {
+	return &dirFS{root: dir, fsys: os.DirFS(dir)}
+}
+
+type dirFS struct {
+	root string
+	fsys fs.FS
+}
+
+func (d *dirFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	root, err := filepath.EvalSymlinks(d.root)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if !within(root, resolved) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errLinkEscapes}
+	}
+	return d.fsys.Open(name)
+}
+
+func (d *dirFS) ReadLink(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return os.Readlink(filepath.Join(d.root, filepath.FromSlash(name)))
+}
+
+// within reports whether path is root or inside it; both must be clean absolute paths
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
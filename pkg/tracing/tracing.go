@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace exporters, named like the values of OTEL_TRACES_EXPORTER
+const (
+	ExporterNone    = "none"
+	ExporterOTLP    = "otlp"
+	ExporterConsole = "console" // pretty-printed JSON spans, for local debugging
+)
+
+// DefaultServiceName is reported unless OTEL_SERVICE_NAME overrides it
+const DefaultServiceName = "grafana-git-sync"
+
+// Attribute keys shared by the instrumented packages
+const (
+	Commit       = attribute.Key("git.commit")
+	Ref          = attribute.Key("git.ref")
+	Path         = attribute.Key("dashboard.path")
+	DashboardUID = attribute.Key("dashboard.uid")
+	Folder       = attribute.Key("grafana.folder")
+	HTTPStatus   = attribute.Key("http.response.status_code")
+)
+
+// Setup installs the global tracer provider for exporter. The OTLP exporter
+// is configured by the standard OTEL_EXPORTER_OTLP_* variables, including
+// OTEL_EXPORTER_OTLP_PROTOCOL (http/protobuf or grpc); the console exporter
+// writes to w. The returned function flushes and stops the provider.
+func Setup(ctx context.Context, exporter string, w io.Writer) (func(context.Context) error, error) {
+	var exp sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(exporter) {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exp, err = newOTLPExporter(ctx)
+	case ExporterConsole, "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(w), stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("invalid traces exporter %q (want none, otlp or console)", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", DefaultServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		slog.Warn("OpenTelemetry error", "error", err)
+	}))
+	slog.Info("Tracing enabled", "exporter", exporter)
+	return provider.Shutdown, nil
+}
+
+// newOTLPExporter creates an OTLP exporter using the protocol from the environment
+func newOTLPExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+	switch protocol {
+	case "http/protobuf", "":
+		return otlptracehttp.New(ctx)
+	case "grpc":
+		return otlptracegrpc.New(ctx)
+	}
+	return nil, fmt.Errorf("unsupported OTLP protocol %q (want http/protobuf or grpc)", protocol)
+}
+
+// ValidExporter reports whether exporter is a supported traces exporter
+func ValidExporter(exporter string) bool {
+	switch strings.ToLower(exporter) {
+	case ExporterNone, ExporterOTLP, ExporterConsole, "stdout", "":
+		return true
+	}
+	return false
+}
+
+// Fail records err on span and marks it as failed
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestSetup(t *testing.T) {
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	if _, err := Setup(context.Background(), "zipkin", io.Discard); err == nil {
+		t.Error("Setup() should reject an unsupported exporter")
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/json")
+	if _, err := Setup(context.Background(), "otlp", io.Discard); err == nil {
+		t.Error("Setup() should reject an unsupported OTLP protocol")
+	}
+
+	shutdown, err := Setup(context.Background(), "none", io.Discard)
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+}
+
+func TestSetup_Console(t *testing.T) {
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	t.Setenv("OTEL_SERVICE_NAME", "dashboards-sync")
+
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), "console", &buf)
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "sync.run")
+	span.SetAttributes(Commit.String("abc1234"))
+	Fail(span, errors.New("upload failed"))
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+
+	for _, want := range []string{`"Name": "sync.run"`, `"git.commit"`, `"upload failed"`, `"dashboards-sync"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("console output does not contain %s:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestSetup_OTLP(t *testing.T) {
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	received := make(chan string, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case received <- r.URL.Path:
+		default:
+		}
+	}))
+	defer collector.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.URL)
+
+	shutdown, err := Setup(context.Background(), "otlp", io.Discard)
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "sync.run")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+
+	select {
+	case path := <-received:
+		if path != "/v1/traces" {
+			t.Errorf("spans exported to %s, want /v1/traces", path)
+		}
+	default:
+		t.Error("no spans exported to the collector")
+	}
+}